@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is read if it exists and no other file is given.
+const defaultConfigFile = "echotest.yaml"
+
+// Config is the runtime configuration. Every value can be set in the YAML
+// config file, by an ECHOTEST_* environment variable and by a flag, each
+// overriding the ones before.
+type Config struct {
+	// Addr is the address the server listens on.
+	Addr string `yaml:"addr"`
+	// OpenBrowser opens the start page in the default browser.
+	OpenBrowser bool `yaml:"open_browser"`
+	// DataDir holds bewertungen.json.
+	DataDir string `yaml:"data_dir"`
+	// ExportArchive keeps a copy of every export, empty disables it.
+	ExportArchive string `yaml:"export_archive"`
+	// Scale is the ID of the grading scale preselected for new exams,
+	// 0 keeps the standard scale.
+	Scale int `yaml:"scale"`
+	// NumberFormat is how numbers are written, "de" (1.234,5) or
+	// "en" (1,234.5). The texts stay German.
+	NumberFormat string `yaml:"number_format"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		Addr:         ":3000",
+		OpenBrowser:  true,
+		DataDir:      ".",
+		NumberFormat: "de",
+	}
+}
+
+// loadConfig reads the configuration from the config file, the environment
+// and the command line arguments. The file is given by -config or
+// ECHOTEST_CONFIG; without either, echotest.yaml is used if it exists.
+func loadConfig(args []string, getenv func(string) string) (Config, error) {
+	var flags Config
+	var configFile string
+	fs := flag.NewFlagSet("echoTest", flag.ContinueOnError)
+	fs.StringVar(&configFile, "config", "", "YAML-Konfigurationsdatei (ECHOTEST_CONFIG)")
+	fs.StringVar(&flags.Addr, "addr", "", "Adresse des Servers, z.B. :3000 (ECHOTEST_ADDR)")
+	fs.BoolVar(&flags.OpenBrowser, "open-browser", false, "Startseite im Browser öffnen (ECHOTEST_OPEN_BROWSER)")
+	fs.StringVar(&flags.DataDir, "data-dir", "", "Verzeichnis für bewertungen.json (ECHOTEST_DATA_DIR)")
+	fs.StringVar(&flags.ExportArchive, "export-archive", "", "Verzeichnis, das jeden Export aufbewahrt (ECHOTEST_EXPORT_ARCHIVE)")
+	fs.IntVar(&flags.Scale, "scale", 0, "ID des vorausgewählten Notenschlüssels (ECHOTEST_SCALE)")
+	fs.StringVar(&flags.NumberFormat, "number-format", "", "Zahlenformat, de oder en (ECHOTEST_NUMBER_FORMAT)")
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	config := defaultConfig()
+	if configFile == "" {
+		configFile = getenv("ECHOTEST_CONFIG")
+	}
+	if err := config.readFile(configFile); err != nil {
+		return Config{}, err
+	}
+	if err := config.readEnv(getenv); err != nil {
+		return Config{}, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			config.Addr = flags.Addr
+		case "open-browser":
+			config.OpenBrowser = flags.OpenBrowser
+		case "data-dir":
+			config.DataDir = flags.DataDir
+		case "export-archive":
+			config.ExportArchive = flags.ExportArchive
+		case "scale":
+			config.Scale = flags.Scale
+		case "number-format":
+			config.NumberFormat = flags.NumberFormat
+		}
+	})
+	return config, config.validate()
+}
+
+// readFile reads the YAML file at path, or echotest.yaml if path is empty
+// and that file exists.
+func (c *Config) readFile(path string) error {
+	optional := path == ""
+	if optional {
+		path = defaultConfigFile
+	}
+	file, err := os.Open(path)
+	if optional && errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// readEnv applies the ECHOTEST_* environment variables that are set.
+func (c *Config) readEnv(getenv func(string) string) error {
+	if value := getenv("ECHOTEST_ADDR"); value != "" {
+		c.Addr = value
+	}
+	if value := getenv("ECHOTEST_OPEN_BROWSER"); value != "" {
+		open, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("ECHOTEST_OPEN_BROWSER: %w", err)
+		}
+		c.OpenBrowser = open
+	}
+	if value := getenv("ECHOTEST_DATA_DIR"); value != "" {
+		c.DataDir = value
+	}
+	if value := getenv("ECHOTEST_EXPORT_ARCHIVE"); value != "" {
+		c.ExportArchive = value
+	}
+	if value := getenv("ECHOTEST_SCALE"); value != "" {
+		scale, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("ECHOTEST_SCALE: %w", err)
+		}
+		c.Scale = scale
+	}
+	if value := getenv("ECHOTEST_NUMBER_FORMAT"); value != "" {
+		c.NumberFormat = value
+	}
+	return nil
+}
+
+func (c Config) validate() error {
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("addr: %w", err)
+	}
+	if c.DataDir == "" {
+		return errors.New("data_dir darf nicht leer sein")
+	}
+	if c.Scale < 0 {
+		return errors.New("scale muss eine ID oder 0 sein")
+	}
+	if c.NumberFormat != "de" && c.NumberFormat != "en" {
+		return fmt.Errorf("number_format: unbekanntes Zahlenformat %q", c.NumberFormat)
+	}
+	return nil
+}
+
+// URL returns the address of the start page, using localhost if the server
+// listens on all interfaces.
+func (c Config) URL() string {
+	host, port, _ := net.SplitHostPort(c.Addr)
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
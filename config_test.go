@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigDefaults(t *testing.T) {
+	config, err := loadConfig(nil, func(string) string { return "" })
+	assert.NoError(t, err)
+	assert.Equal(t, defaultConfig(), config)
+	assert.Equal(t, "http://localhost:3000", config.URL())
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "echotest.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("addr: :4000\nopen_browser: false\ndata_dir: /srv/noten\nnumber_format: en\nscale: 2\n"), 0o644))
+	env := map[string]string{
+		"ECHOTEST_CONFIG":         path,
+		"ECHOTEST_ADDR":           "127.0.0.1:5000",
+		"ECHOTEST_EXPORT_ARCHIVE": "/srv/exporte",
+	}
+
+	config, err := loadConfig([]string{"-addr", "127.0.0.1:6000", "-number-format", "de"}, func(key string) string { return env[key] })
+	assert.NoError(t, err)
+	assert.Equal(t, Config{
+		Addr:          "127.0.0.1:6000",
+		OpenBrowser:   false,
+		DataDir:       "/srv/noten",
+		ExportArchive: "/srv/exporte",
+		Scale:         2,
+		NumberFormat:  "de",
+	}, config)
+	assert.Equal(t, "http://127.0.0.1:6000", config.URL())
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	noEnv := func(string) string { return "" }
+
+	unknown := filepath.Join(dir, "unknown.yaml")
+	assert.NoError(t, os.WriteFile(unknown, []byte("port: 3000\n"), 0o644))
+	_, err := loadConfig([]string{"-config", unknown}, noEnv)
+	assert.ErrorContains(t, err, "port")
+
+	_, err = loadConfig([]string{"-config", filepath.Join(dir, "missing.yaml")}, noEnv)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = loadConfig([]string{"-number-format", "fr", "-data-dir", dir}, noEnv)
+	assert.ErrorContains(t, err, "fr")
+
+	_, err = loadConfig([]string{"-data-dir", dir}, func(key string) string {
+		if key == "ECHOTEST_OPEN_BROWSER" {
+			return "vielleicht"
+		}
+		return ""
+	})
+	assert.ErrorContains(t, err, "ECHOTEST_OPEN_BROWSER")
+}
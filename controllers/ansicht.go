@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"echoTest/model"
+
+	"github.com/chasefleming/elem-go"
+	"github.com/chasefleming/elem-go/attrs"
+	"github.com/chasefleming/elem-go/htmx"
+	"github.com/labstack/echo/v4"
+)
+
+// Ansicht is the sort order and filter of the Bewertungen table. It is kept
+// in the query of the exam page so that a view can be bookmarked.
+type Ansicht struct {
+	// Sortierung is one of the keys of sortierungen, empty keeps the order
+	// of input.
+	Sortierung string
+	Absteigend bool
+	// Filter is one of the keys of filter, empty shows every Bewertung.
+	Filter string
+}
+
+var sortierungen = []struct{ Key, Label string }{
+	{"", "Eingabe"},
+	{"nachname", "Nachname"},
+	{"vorname", "Vorname"},
+	{"prozent", "Gesamt-Prozent"},
+	{"note", "Gesamt-Note"},
+}
+
+var filter = []struct{ Key, Label string }{
+	{"", "Alle"},
+	{"defizit", "Nur Defizite"},
+	{"gewertet", "Nur gewertete"},
+	{"ungewertet", "Nur nicht gewertete"},
+}
+
+// parseAnsicht reads the Ansicht from the query, unknown values are ignored.
+func parseAnsicht(query url.Values) Ansicht {
+	var ansicht Ansicht
+	for _, sortierung := range sortierungen {
+		if sortierung.Key == query.Get("sort") {
+			ansicht.Sortierung = sortierung.Key
+		}
+	}
+	for _, f := range filter {
+		if f.Key == query.Get("filter") {
+			ansicht.Filter = f.Key
+		}
+	}
+	ansicht.Absteigend = query.Get("dir") == "desc"
+	return ansicht
+}
+
+// Anwenden returns the Bewertungen matching the filter in the sort order of
+// the Ansicht. Equal rows keep the order of input.
+func (a Ansicht) Anwenden(notenschluessel model.Notenschluessel, bewertungen []model.Bewertung) []model.Bewertung {
+	result := []model.Bewertung{}
+	for _, bewertung := range bewertungen {
+		switch {
+		case a.Filter == "defizit" && !notenschluessel.Defizit(bewertung.GesamtNote),
+			a.Filter == "gewertet" && !bewertung.Gewertet,
+			a.Filter == "ungewertet" && bewertung.Gewertet:
+			continue
+		}
+		result = append(result, bewertung)
+	}
+
+	var compare func(x, y model.Bewertung) int
+	switch a.Sortierung {
+	case "nachname":
+		compare = func(x, y model.Bewertung) int {
+			if c := strings.Compare(strings.ToLower(x.Nachname), strings.ToLower(y.Nachname)); c != 0 {
+				return c
+			}
+			return strings.Compare(strings.ToLower(x.Vorname), strings.ToLower(y.Vorname))
+		}
+	case "vorname":
+		compare = func(x, y model.Bewertung) int {
+			if c := strings.Compare(strings.ToLower(x.Vorname), strings.ToLower(y.Vorname)); c != 0 {
+				return c
+			}
+			return strings.Compare(strings.ToLower(x.Nachname), strings.ToLower(y.Nachname))
+		}
+	case "prozent":
+		compare = func(x, y model.Bewertung) int { return compareFloat(x.GesamtProzent, y.GesamtProzent) }
+	case "note":
+		compare = func(x, y model.Bewertung) int { return compareFloat(x.GesamtNote.Wert, y.GesamtNote.Wert) }
+	default:
+		if a.Absteigend {
+			for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+				result[i], result[j] = result[j], result[i]
+			}
+		}
+		return result
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if a.Absteigend {
+			return compare(result[i], result[j]) > 0
+		}
+		return compare(result[i], result[j]) < 0
+	})
+	return result
+}
+
+func compareFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// isBewertungenRequest reports whether htmx only asks for the rows of the
+// Bewertungen table. History restores need the whole page.
+func isBewertungenRequest(c echo.Context) bool {
+	header := c.Request().Header
+	return isHTMX(c) && header.Get("HX-Target") == "bewertungen" && header.Get("HX-History-Restore-Request") != "true"
+}
+
+// createBewertungenBodyNode renders the rows of the Bewertungen table.
+func createBewertungenBodyNode(locale Locale, exam model.Exam, bewertungen []model.Bewertung) elem.Node {
+	rows := elem.TransformEach(bewertungen, func(bewertung model.Bewertung) elem.Node {
+		return createBewertungNode(locale, bewertung)
+	})
+	if len(rows) == 0 {
+		rows = []elem.Node{elem.Tr(nil,
+			elem.Td(attrs.Props{
+				attrs.ColSpan: strconv.Itoa(3*len(exam.Sections) + 6),
+				attrs.Class:   "has-text-grey",
+			}, elem.Text("Keine Bewertungen")),
+		)}
+	}
+	return elem.TBody(attrs.Props{attrs.ID: "bewertungen"}, rows...)
+}
+
+// createAnsichtFormNode renders the controls that sort and filter the
+// Bewertungen table. Without JavaScript the form reloads the whole page.
+func createAnsichtFormNode(exam model.Exam, ansicht Ansicht) elem.Node {
+	auswahl := func(name, label string, options []elem.Node) elem.Node {
+		return elem.Div(attrs.Props{attrs.Class: "field"},
+			elem.Label(attrs.Props{attrs.Class: "label is-small"}, elem.Text(label)),
+			elem.Div(attrs.Props{attrs.Class: "select is-small"},
+				elem.Select(attrs.Props{attrs.Name: name}, options...),
+			),
+		)
+	}
+	option := func(value, label string, selected bool) elem.Node {
+		return elem.Option(attrs.Props{attrs.Value: value, attrs.Selected: strconv.FormatBool(selected)}, elem.Text(label))
+	}
+
+	var sortOptions, filterOptions []elem.Node
+	for _, sortierung := range sortierungen {
+		sortOptions = append(sortOptions, option(sortierung.Key, sortierung.Label, sortierung.Key == ansicht.Sortierung))
+	}
+	for _, f := range filter {
+		filterOptions = append(filterOptions, option(f.Key, f.Label, f.Key == ansicht.Filter))
+	}
+
+	return elem.Form(attrs.Props{
+		attrs.Method:   "get",
+		attrs.Action:   examURL(exam),
+		attrs.Class:    "field is-grouped is-grouped-multiline",
+		htmx.HXGet:     examURL(exam),
+		htmx.HXTrigger: "change",
+		htmx.HXTarget:  "#bewertungen",
+		htmx.HXSwap:    "outerHTML",
+		htmx.HXPushURL: "true",
+	},
+		auswahl("sort", "Sortieren nach", sortOptions),
+		auswahl("dir", "Reihenfolge", []elem.Node{
+			option("asc", "Aufsteigend", !ansicht.Absteigend),
+			option("desc", "Absteigend", ansicht.Absteigend),
+		}),
+		auswahl("filter", "Anzeigen", filterOptions),
+		elem.NoScript(nil,
+			elem.Button(attrs.Props{attrs.Type: "submit", attrs.Class: "button is-small"}, elem.Text("Anwenden")),
+		),
+	)
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"echoTest/model"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAnsicht(t *testing.T) {
+	ansicht := parseAnsicht(url.Values{"sort": {"note"}, "dir": {"desc"}, "filter": {"defizit"}})
+	assert.Equal(t, Ansicht{Sortierung: "note", Absteigend: true, Filter: "defizit"}, ansicht)
+
+	assert.Equal(t, Ansicht{}, parseAnsicht(url.Values{"sort": {"<script>"}, "filter": {"alle"}}))
+}
+
+func TestAnsichtAnwenden(t *testing.T) {
+	bewertungen := []model.Bewertung{
+		{ID: 1, Nachname: "Müller", Vorname: "Tom", GesamtProzent: 40, GesamtNote: model.Note{Name: "5", Wert: 5}, Gewertet: true},
+		{ID: 2, Nachname: "adam", Vorname: "Lea", GesamtProzent: 90, GesamtNote: model.Note{Name: "1", Wert: 1}, Gewertet: true},
+		{ID: 3, Nachname: "Beck", Vorname: "Ina", GesamtProzent: 70, GesamtNote: model.Note{Name: "3", Wert: 3}},
+	}
+	ids := func(bewertungen []model.Bewertung) []int {
+		var result []int
+		for _, bewertung := range bewertungen {
+			result = append(result, bewertung.ID)
+		}
+		return result
+	}
+
+	assert.Equal(t, []int{1, 2, 3}, ids(Ansicht{}.Anwenden(model.StandardNotenschluessel, bewertungen)))
+	assert.Equal(t, []int{2, 3, 1}, ids(Ansicht{Sortierung: "nachname"}.Anwenden(model.StandardNotenschluessel, bewertungen)))
+	assert.Equal(t, []int{2, 3, 1}, ids(Ansicht{Sortierung: "prozent", Absteigend: true}.Anwenden(model.StandardNotenschluessel, bewertungen)))
+	assert.Equal(t, []int{2, 3, 1}, ids(Ansicht{Sortierung: "note"}.Anwenden(model.StandardNotenschluessel, bewertungen)))
+	assert.Equal(t, []int{1}, ids(Ansicht{Filter: "defizit"}.Anwenden(model.StandardNotenschluessel, bewertungen)))
+	assert.Equal(t, []int{3}, ids(Ansicht{Filter: "ungewertet"}.Anwenden(model.StandardNotenschluessel, bewertungen)))
+	// The input is left untouched
+	assert.Equal(t, []int{1, 2, 3}, ids(bewertungen))
+}
+
+func TestRenderBewertungenRoutePartial(t *testing.T) {
+	exam := createTestExam(t)
+	for _, nachname := range []string{"Zander", "Albers"} {
+		_, err := testController.store.Save(model.Bewertung{ExamID: exam.ID, Vorname: "Kim", Nachname: nachname, Gewertet: true})
+		assert.NoError(t, err)
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, examURL(exam)+"?sort=nachname", nil)
+	req.Header.Set("HX-Request", "true")
+	req.Header.Set("HX-Target", "bewertungen")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(exam.ID))
+
+	assert.NoError(t, testController.renderBewertungenRoute(c))
+	body := rec.Body.String()
+	assert.True(t, strings.HasPrefix(body, `<tbody id="bewertungen">`))
+	assert.Less(t, strings.Index(body, "Albers"), strings.Index(body, "Zander"))
+}
@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"echoTest/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+// The JSON API under /api/v1 returns the stored records as they are. Errors
+// have the body {"message": "..."}, invalid input additionally lists the
+// offending fields under "errors" with status 422.
+
+// apiExam is the writable part of an Exam. Datum is either a date like
+// 2024-05-31 or an RFC 3339 timestamp.
+type apiExam struct {
+	Titel             string
+	Fach              string
+	Klasse            string
+	Lehrkraft         string
+	Datum             string
+	Sections          []model.Section
+	NotenschluesselID int
+}
+
+// apiBewertung is the writable part of a Bewertung. Punkte holds the points
+// of every section of the exam in order.
+type apiBewertung struct {
+	Vorname   string
+	Nachname  string
+	Punkte    []float64
+	Kommentar string
+}
+
+// apiValidationError is the body of a 422 response.
+type apiValidationError struct {
+	Message string      `json:"message"`
+	Errors  FieldErrors `json:"errors"`
+}
+
+func (h *Controller) registerAPI(g *echo.Group) {
+	api := g.Group("/api/v1")
+	api.GET("/exams", h.apiListExamsRoute)
+	api.POST("/exams", h.apiCreateExamRoute)
+	api.GET("/exams/:id", h.apiGetExamRoute)
+	api.PUT("/exams/:id", h.apiUpdateExamRoute)
+	api.DELETE("/exams/:id", h.apiDeleteExamRoute)
+	api.GET("/exams/:id/bewertungen", h.apiListBewertungenRoute)
+	api.POST("/exams/:id/bewertungen", h.apiCreateBewertungRoute)
+	api.GET("/bewertungen/:id", h.apiGetBewertungRoute)
+	api.PUT("/bewertungen/:id", h.apiUpdateBewertungRoute)
+	api.DELETE("/bewertungen/:id", h.apiDeleteBewertungRoute)
+	api.POST("/bewertungen/:id/toggle", h.apiToggleWertungRoute)
+}
+
+// decodeJSON reads the request body into v and rejects unknown fields, so
+// typos do not silently reset a value.
+func decodeJSON(c echo.Context, v any) error {
+	decoder := json.NewDecoder(c.Request().Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Ungültiges JSON: "+err.Error())
+	}
+	return nil
+}
+
+func validationError(c echo.Context, fieldErrors FieldErrors) error {
+	return c.JSON(http.StatusUnprocessableEntity, apiValidationError{
+		Message: "Ungültige Eingabe",
+		Errors:  fieldErrors,
+	})
+}
+
+func (h *Controller) apiListExamsRoute(c echo.Context) error {
+	exams, err := h.store.ListExams()
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, exams)
+}
+
+func (h *Controller) apiGetExamRoute(c echo.Context) error {
+	exam, err := h.loadExam(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, exam)
+}
+
+func (h *Controller) apiCreateExamRoute(c echo.Context) error {
+	var input apiExam
+	if err := decodeJSON(c, &input); err != nil {
+		return err
+	}
+	exam, fieldErrors := h.checkExam(model.Exam{}, input)
+	if len(fieldErrors) > 0 {
+		return validationError(c, fieldErrors)
+	}
+	exam, err := h.store.SaveExam(exam)
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(echo.HeaderLocation, "/api/v1"+examURL(exam))
+	return c.JSON(http.StatusCreated, exam)
+}
+
+// apiUpdateExamRoute replaces the exam and recalculates its Bewertungen.
+// Points stay with the section at the same position.
+func (h *Controller) apiUpdateExamRoute(c echo.Context) error {
+	exam, err := h.loadExam(c)
+	if err != nil {
+		return err
+	}
+	var input apiExam
+	if err := decodeJSON(c, &input); err != nil {
+		return err
+	}
+	exam, fieldErrors := h.checkExam(exam, input)
+	if len(fieldErrors) > 0 {
+		return validationError(c, fieldErrors)
+	}
+	if err := h.store.UpdateExam(exam); err != nil {
+		return err
+	}
+	if err := h.recomputeExam(exam); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, exam)
+}
+
+func (h *Controller) apiDeleteExamRoute(c echo.Context) error {
+	exam, err := h.loadExam(c)
+	if err != nil {
+		return err
+	}
+	if err := h.store.DeleteExam(exam.ID); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// checkExam validates the input and applies it to the exam.
+func (h *Controller) checkExam(exam model.Exam, input apiExam) (model.Exam, FieldErrors) {
+	fieldErrors := FieldErrors{}
+	exam.Titel = strings.TrimSpace(input.Titel)
+	exam.Fach = input.Fach
+	exam.Klasse = input.Klasse
+	exam.Lehrkraft = input.Lehrkraft
+	exam.Sections = input.Sections
+	exam.NotenschluesselID = input.NotenschluesselID
+
+	if exam.Titel == "" {
+		fieldErrors["titel"] = "Bitte einen Titel eingeben"
+	}
+	if message := validateSections(exam.Sections); message != "" {
+		fieldErrors["sections"] = message
+	}
+	if exam.NotenschluesselID == 0 {
+		exam.NotenschluesselID = h.notenschluessel.ID
+	} else if _, err := h.store.LoadNotenschluessel(exam.NotenschluesselID); err != nil {
+		fieldErrors["notenschluessel"] = "Notenschlüssel nicht gefunden"
+	}
+	exam.Datum = time.Time{}
+	if input.Datum != "" {
+		datum, err := time.Parse(datumLayout, input.Datum)
+		if err != nil {
+			datum, err = time.Parse(time.RFC3339, input.Datum)
+		}
+		if err != nil {
+			fieldErrors["datum"] = "Ungültiges Datum"
+		}
+		exam.Datum = datum
+	}
+	return exam, fieldErrors
+}
+
+func (h *Controller) apiListBewertungenRoute(c echo.Context) error {
+	exam, err := h.loadExam(c)
+	if err != nil {
+		return err
+	}
+	bewertungen, err := h.store.List(exam.ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, bewertungen)
+}
+
+func (h *Controller) apiGetBewertungRoute(c echo.Context) error {
+	bewertung, err := h.loadBewertung(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, bewertung)
+}
+
+func (h *Controller) apiCreateBewertungRoute(c echo.Context) error {
+	exam, err := h.loadExam(c)
+	if err != nil {
+		return err
+	}
+	bewertung, fieldErrors, err := h.parseAPIBewertung(c, exam, 0)
+	if err != nil {
+		return err
+	}
+	if len(fieldErrors) > 0 {
+		return validationError(c, fieldErrors)
+	}
+	bewertung, err = h.store.Save(bewertung)
+	if errors.Is(err, model.ErrExists) {
+		return validationError(c, FieldErrors{"nachname": "Diese Person ist bereits eingetragen"})
+	}
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(echo.HeaderLocation, "/api/v1/bewertungen/"+strconv.Itoa(bewertung.ID))
+	return c.JSON(http.StatusCreated, bewertung)
+}
+
+// apiUpdateBewertungRoute replaces names, points and comment of the
+// Bewertung, whether it is gewertet is changed by apiToggleWertungRoute.
+func (h *Controller) apiUpdateBewertungRoute(c echo.Context) error {
+	bewertung, err := h.loadBewertung(c)
+	if err != nil {
+		return err
+	}
+	exam, err := h.store.LoadExam(bewertung.ExamID)
+	if err != nil {
+		return err
+	}
+	edited, fieldErrors, err := h.parseAPIBewertung(c, exam, bewertung.ID)
+	if err != nil {
+		return err
+	}
+	if len(fieldErrors) > 0 {
+		return validationError(c, fieldErrors)
+	}
+	edited.ID = bewertung.ID
+	edited.Gewertet = bewertung.Gewertet
+	edited.Grund = bewertung.Grund
+	if err := h.store.Update(edited); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, edited)
+}
+
+func (h *Controller) apiDeleteBewertungRoute(c echo.Context) error {
+	bewertung, err := h.loadBewertung(c)
+	if err != nil {
+		return err
+	}
+	if err := h.store.Delete(bewertung.ID); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// apiToggleWertungRoute flips Gewertet. The optional body {"Grund": "..."}
+// gives the reason when the Bewertung is excluded.
+func (h *Controller) apiToggleWertungRoute(c echo.Context) error {
+	bewertung, err := h.loadBewertung(c)
+	if err != nil {
+		return err
+	}
+	var input struct{ Grund string }
+	if c.Request().ContentLength > 0 {
+		if err := decodeJSON(c, &input); err != nil {
+			return err
+		}
+	}
+	bewertung, err = h.store.ToggleGewertet(bewertung.ID, strings.TrimSpace(input.Grund))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, bewertung)
+}
+
+// parseAPIBewertung is parseBewertungen for a JSON body. The input is turned
+// into form values so that it is validated and graded by checkBewertung
+// exactly like the form.
+func (h *Controller) parseAPIBewertung(c echo.Context, exam model.Exam, id int) (model.Bewertung, FieldErrors, error) {
+	var input apiBewertung
+	if err := decodeJSON(c, &input); err != nil {
+		return model.Bewertung{}, nil, err
+	}
+	bewertungen, err := h.store.List(exam.ID)
+	if err != nil {
+		return model.Bewertung{}, nil, err
+	}
+	notenschluessel, err := h.examNotenschluessel(exam)
+	if err != nil {
+		return model.Bewertung{}, nil, err
+	}
+	values := url.Values{
+		"vorname":   {input.Vorname},
+		"nachname":  {input.Nachname},
+		"kommentar": {input.Kommentar},
+	}
+	for i, punkte := range input.Punkte {
+		values.Set(punkteField(i), strconv.FormatFloat(punkte, 'f', -1, 64))
+	}
+	bewertung, fieldErrors := checkBewertung(h.locale, exam, notenschluessel, bewertungen, values, id)
+	if len(input.Punkte) > len(exam.Sections) {
+		if fieldErrors == nil {
+			fieldErrors = FieldErrors{}
+		}
+		fieldErrors["punkte"] = "Die Klassenarbeit hat nur " + strconv.Itoa(len(exam.Sections)) + " Teile"
+	}
+	return bewertung, fieldErrors, nil
+}
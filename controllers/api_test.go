@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"echoTest/model"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// apiRequest sends the request through a router with the API registered.
+func apiRequest(t *testing.T, method, target, body string) *httptest.ResponseRecorder {
+	e := echo.New()
+	testController.registerAPI(e.Group(""))
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPIExams(t *testing.T) {
+	rec := apiRequest(t, http.MethodPost, "/api/v1/exams",
+		`{"Titel": "Mathearbeit", "Datum": "2024-05-31", "Sections": [{"Name": "A", "Max": 10, "Gewichtung": 100}]}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var exam model.Exam
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &exam))
+	t.Cleanup(func() { testController.store.DeleteExam(exam.ID) })
+	assert.Equal(t, "/api/v1/exams/"+strconv.Itoa(exam.ID), rec.Header().Get(echo.HeaderLocation))
+	assert.Equal(t, model.StandardNotenschluessel.ID, exam.NotenschluesselID)
+
+	rec = apiRequest(t, http.MethodPut, "/api/v1/exams/"+strconv.Itoa(exam.ID),
+		`{"Titel": "Mathearbeit", "Sections": [{"Name": "A", "Max": 10, "Gewichtung": 90}]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"sections"`)
+
+	rec = apiRequest(t, http.MethodPut, "/api/v1/exams/"+strconv.Itoa(exam.ID), `{"Titel": "Mathe", "Unbekannt": 1}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = apiRequest(t, http.MethodDelete, "/api/v1/exams/"+strconv.Itoa(exam.ID), "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = apiRequest(t, http.MethodGet, "/api/v1/exams/"+strconv.Itoa(exam.ID), "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"message": "Klassenarbeit nicht gefunden"}`, rec.Body.String())
+}
+
+func TestAPIBewertungen(t *testing.T) {
+	exam := createTestExam(t)
+	bewertungenURL := "/api/v1/exams/" + strconv.Itoa(exam.ID) + "/bewertungen"
+
+	rec := apiRequest(t, http.MethodPost, bewertungenURL, `{"Vorname": "Anna", "Nachname": "Muster", "Punkte": [10, 30]}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var bewertung model.Bewertung
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &bewertung))
+	assert.Equal(t, 75.0, bewertung.GesamtProzent)
+	assert.True(t, bewertung.Gewertet)
+	bewertungURL := "/api/v1/bewertungen/" + strconv.Itoa(bewertung.ID)
+
+	rec = apiRequest(t, http.MethodPost, bewertungenURL, `{"Vorname": "Anna", "Nachname": "Muster", "Punkte": [25]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	var validation apiValidationError
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &validation))
+	assert.Equal(t, "Diese Person ist bereits eingetragen", validation.Errors["nachname"])
+	assert.Equal(t, "Höchstens 20,00 Punkte", validation.Errors[punkteField(0)])
+	assert.Equal(t, "Bitte Punkte eingeben", validation.Errors[punkteField(1)])
+
+	rec = apiRequest(t, http.MethodPut, bewertungURL, `{"Vorname": "Anna", "Nachname": "Muster", "Punkte": [20, 30], "Kommentar": "Sehr gut"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &bewertung))
+	assert.Equal(t, 100.0, bewertung.GesamtProzent)
+	assert.Equal(t, "Sehr gut", bewertung.Kommentar)
+
+	rec = apiRequest(t, http.MethodPost, bewertungURL+"/toggle", `{"Grund": "krank"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &bewertung))
+	assert.False(t, bewertung.Gewertet)
+	assert.Equal(t, "krank", bewertung.Grund)
+
+	rec = apiRequest(t, http.MethodGet, bewertungenURL, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var bewertungen []model.Bewertung
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &bewertungen))
+	assert.Len(t, bewertungen, 1)
+
+	rec = apiRequest(t, http.MethodDelete, bewertungURL, "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = apiRequest(t, http.MethodGet, bewertungURL, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"embed"
+
+	"github.com/labstack/echo/v4"
+)
+
+// The vendored files are fetched once with go generate and committed, so
+// the binary serves them without internet access. Their names contain the
+// version, which allows caching them forever.
+//
+//go:generate curl -fsSL -o assets/htmx-1.9.12.min.js https://unpkg.com/htmx.org@1.9.12/dist/htmx.min.js
+//go:generate curl -fsSL -o assets/bulma-0.9.4.min.css https://cdn.jsdelivr.net/npm/bulma@0.9.4/css/bulma.min.css
+
+//go:embed all:assets
+var assetsFS embed.FS
+
+// registerAssets serves the embedded files below /assets.
+func registerAssets(e *echo.Echo) {
+	group := e.Group("/assets", cacheAssets)
+	group.StaticFS("/", echo.MustSubFS(assetsFS, "assets"))
+}
+
+// cacheAssets lets browsers keep the versioned files for a year.
+func cacheAssets(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=31536000, immutable")
+		return next(c)
+	}
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chasefleming/elem-go"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetsRoute(t *testing.T) {
+	e := echo.New()
+	registerAssets(e)
+
+	files := map[string]string{
+		"/assets/htmx-1.9.12.min.js":  `version:"1.9.12"`,
+		"/assets/bulma-0.9.4.min.css": "bulma.io v0.9.4",
+	}
+	page := renderPage(elem.None())
+	for path, marker := range files {
+		assert.Contains(t, page, `"`+path+`"`)
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if assert.Equal(t, http.StatusOK, rec.Code, path) {
+			assert.Contains(t, rec.Body.String(), marker)
+			assert.Equal(t, "public, max-age=31536000, immutable", rec.Header().Get(echo.HeaderCacheControl))
+		}
+	}
+	assert.NotContains(t, page, "https://")
+}
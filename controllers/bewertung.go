@@ -1,10 +1,15 @@
 package controllers
 
 import (
-	"echoTest/model"
-	"fmt"
+	"errors"
+	"html"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+
+	"echoTest/model"
 
 	"github.com/chasefleming/elem-go"
 	"github.com/chasefleming/elem-go/attrs"
@@ -12,193 +17,468 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-func UpdateGewertetRoute(bewertung model.Bewertung) elem.Node {
-	checkbox := elem.Input(attrs.Props{
-		attrs.Type:    "checkbox",
-		attrs.Checked: strconv.FormatBool(bewertung.Gewertet),
-		htmx.HXPost:   "/toggle/" + strconv.Itoa(bewertung.ID),
-		htmx.HXTarget: "#bewertung-" + strconv.Itoa(bewertung.ID),
-	})
-	return checkbox
+func (h *Controller) renderBewertungenRoute(c echo.Context) error {
+	exam, err := h.loadExam(c)
+	if err != nil {
+		return err
+	}
+	bewertungen, err := h.store.List(exam.ID)
+	if err != nil {
+		return err
+	}
+	notenschluessel, err := h.examNotenschluessel(exam)
+	if err != nil {
+		return err
+	}
+	ansicht := parseAnsicht(c.QueryParams())
+	if isBewertungenRequest(c) {
+		return c.HTML(http.StatusOK, createBewertungenBodyNode(h.locale, exam, ansicht.Anwenden(notenschluessel, bewertungen)).Render())
+	}
+	return c.HTML(http.StatusOK, renderBewertungen(h.locale, exam, notenschluessel, bewertungen, ansicht, createBewertungFormNode(exam, nil, nil)))
 }
 
-func RenderBewertungenRoute(c echo.Context) error {
-	return c.HTML(http.StatusOK, renderBewertungen(Bewertungen))
+func (h *Controller) toggleWertungRoute(c echo.Context) error {
+	bewertung, err := h.loadBewertung(c)
+	if err != nil {
+		return err
+	}
+	// The reason is asked for by hx-prompt when a Bewertung is excluded
+	bewertung, err = h.store.ToggleGewertet(bewertung.ID, strings.TrimSpace(c.Request().Header.Get("HX-Prompt")))
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set("HX-Trigger", statistikChanged)
+	return c.HTML(http.StatusOK, createBewertungNode(h.locale, bewertung).Render())
 }
 
-func ToggleWertungRoute(c echo.Context) error {
+func (h *Controller) renderBewertungRoute(c echo.Context) error {
+	bewertung, err := h.loadBewertung(c)
+	if err != nil {
+		return err
+	}
+	return c.HTML(http.StatusOK, createBewertungNode(h.locale, bewertung).Render())
+}
+
+func (h *Controller) editBewertungRoute(c echo.Context) error {
+	bewertung, err := h.loadBewertung(c)
+	if err != nil {
+		return err
+	}
+	exam, err := h.store.LoadExam(bewertung.ExamID)
+	if err != nil {
+		return err
+	}
+	return c.HTML(http.StatusOK, createBewertungEditNode(exam, bewertung, bewertungValues(h.locale, bewertung), nil).Render())
+}
+
+// updateBewertungRoute stores the edited row and swaps in the recalculated
+// result.
+func (h *Controller) updateBewertungRoute(c echo.Context) error {
+	bewertung, err := h.loadBewertung(c)
+	if err != nil {
+		return err
+	}
+	exam, err := h.store.LoadExam(bewertung.ExamID)
+	if err != nil {
+		return err
+	}
+	edited, fieldErrors, err := h.parseBewertungen(c, exam, bewertung.ID)
+	if err != nil {
+		return err
+	}
+	if len(fieldErrors) > 0 {
+		values, _ := c.FormParams()
+		return c.HTML(http.StatusOK, createBewertungEditNode(exam, bewertung, values, fieldErrors).Render())
+	}
+	edited.ID = bewertung.ID
+	edited.Gewertet = bewertung.Gewertet
+	edited.Grund = bewertung.Grund
+	if err := h.store.Update(edited); err != nil {
+		return err
+	}
+	c.Response().Header().Set("HX-Trigger", statistikChanged)
+	return c.HTML(http.StatusOK, createBewertungNode(h.locale, edited).Render())
+}
+
+func (h *Controller) deleteBewertungRoute(c echo.Context) error {
+	bewertung, err := h.loadBewertung(c)
+	if err != nil {
+		return err
+	}
+	if err := h.store.Delete(bewertung.ID); err != nil {
+		return err
+	}
+	c.Response().Header().Set("HX-Trigger", statistikChanged)
+	return c.NoContent(http.StatusOK)
+}
+
+// loadBewertung returns the Bewertung addressed by the :id route parameter.
+func (h *Controller) loadBewertung(c echo.Context) (model.Bewertung, error) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	var updatedBewertung model.Bewertung
-	for i, bewertung := range Bewertungen {
-		if bewertung.ID == id {
-			Bewertungen[i].Gewertet = !bewertung.Gewertet
-			updatedBewertung = Bewertungen[i]
-			break
+	bewertung, err := h.store.Load(id)
+	if errors.Is(err, model.ErrNotFound) {
+		return model.Bewertung{}, echo.NewHTTPError(http.StatusNotFound, "Bewertung nicht gefunden")
+	}
+	return bewertung, err
+}
+
+func bewertungURL(bewertung model.Bewertung) string {
+	return "/bewertung/" + strconv.Itoa(bewertung.ID)
+}
+
+func (h *Controller) addBewertungRoute(c echo.Context) error {
+	exam, err := h.loadExam(c)
+	if err != nil {
+		return err
+	}
+	new, fieldErrors, err := h.parseBewertungen(c, exam, 0)
+	if err != nil {
+		return err
+	}
+	if len(fieldErrors) == 0 {
+		_, err = h.store.Save(new)
+		if errors.Is(err, model.ErrExists) {
+			fieldErrors["nachname"] = "Diese Person ist bereits eingetragen"
+		} else if err != nil {
+			return err
+		}
+	}
+
+	// Show the form again with the messages next to the invalid inputs
+	if len(fieldErrors) > 0 {
+		values, _ := c.FormParams()
+		form := createBewertungFormNode(exam, values, fieldErrors)
+		if isHTMX(c) {
+			return c.HTML(http.StatusOK, form.Render())
+		}
+		bewertungen, err := h.store.List(exam.ID)
+		if err != nil {
+			return err
 		}
+		notenschluessel, err := h.examNotenschluessel(exam)
+		if err != nil {
+			return err
+		}
+		return c.HTML(http.StatusUnprocessableEntity, renderBewertungen(h.locale, exam, notenschluessel, bewertungen, Ansicht{}, form))
+	}
+
+	if isHTMX(c) {
+		c.Response().Header().Set("HX-Redirect", examURL(exam))
+		return c.NoContent(http.StatusOK)
 	}
-	return c.HTML(http.StatusOK, createBewertungNode(updatedBewertung).Render())
+	return c.Redirect(http.StatusSeeOther, examURL(exam))
 }
-func addBewertungRoute(c echo.Context) error {
-	new := parseBewertungen(c)
-	if new.Nachname != "" {
-		Bewertungen = append(Bewertungen, new)
+
+// parseBewertungen reads a Bewertung from the form and validates it. id is
+// the ID of the Bewertung being edited, or 0 for a new one. Invalid input is
+// reported in FieldErrors, the error is only set if the store fails.
+func (h *Controller) parseBewertungen(c echo.Context, exam model.Exam, id int) (model.Bewertung, FieldErrors, error) {
+	bewertungen, err := h.store.List(exam.ID)
+	if err != nil {
+		return model.Bewertung{}, nil, err
+	}
+	notenschluessel, err := h.examNotenschluessel(exam)
+	if err != nil {
+		return model.Bewertung{}, nil, err
 	}
-	return c.Redirect(http.StatusSeeOther, "/")
+	values, _ := c.FormParams()
+	bewertung, fieldErrors := checkBewertung(h.locale, exam, notenschluessel, bewertungen, values, id)
+	return bewertung, fieldErrors, nil
 }
-func parseBewertungen(c echo.Context) Bewertung {
-	newName := validateName(c)
-	vorname := c.FormValue("vorname")
-	if MaxPunkte.HvMax == 0.00 {
-		hvMax, _ := strconv.ParseFloat(c.FormValue("hv_max"), 64)
-		lvMax, _ := strconv.ParseFloat(c.FormValue("lv_max"), 64)
-		hvGewichtung, _ := strconv.ParseFloat(c.FormValue("hv_gewichtung"), 64)
-		lvGewichtung, _ := strconv.ParseFloat(c.FormValue("lv_gewichtung"), 64)
-		MaxPunkte.HvMax = hvMax
-		MaxPunkte.LvMax = lvMax
-		MaxPunkte.LvGewichtung = lvGewichtung
-		MaxPunkte.HvGewichtung = hvGewichtung
-	}
-	hvPunkte, _ := strconv.ParseFloat(c.FormValue("hv_punkte"), 64)
-	lvPunkte, _ := strconv.ParseFloat(c.FormValue("lv_punkte"), 64)
-	hvProzent := 100.00 / maxPunkte.HvMax * hvPunkte
-	lvProzent := 100.00 / maxPunkte.LvMax * lvPunkte
-	hvNote := setNote(hvProzent)
-	lvNote := setNote(lvProzent)
-	gesamtProzent := hvProzent*maxPunkte.HvGewichtung/100 + lvProzent*maxPunkte.LvGewichtung/100
-	gesamtNote := setNote(gesamtProzent)
 
-	// Create a new Bewertung struct
-	return model.Bewertung{
-		ID:            len(bewertungen) + 1,
-		Vorname:       string(vorname),
-		Nachname:      string(newName),
-		HvPunkte:      hvPunkte,
-		HvProzent:     hvProzent,
-		HvNote:        int(hvNote),
-		LvPunkte:      lvPunkte,
-		LvProzent:     lvProzent,
-		LvNote:        int(lvNote),
-		GesamtProzent: gesamtProzent,
-		GesamtNote:    int(gesamtNote),
-		Gewertet:      true,
+// checkBewertung validates the values of a Bewertung against the exam and
+// the Bewertungen already stored for it and grades the result.
+func checkBewertung(locale Locale, exam model.Exam, notenschluessel model.Notenschluessel, bewertungen []model.Bewertung, values url.Values, id int) (model.Bewertung, FieldErrors) {
+	fieldErrors := FieldErrors{}
+	newName := validateName(values, bewertungen, id)
+	if strings.TrimSpace(values.Get("nachname")) == "" {
+		fieldErrors["nachname"] = "Bitte einen Nachnamen eingeben"
+	} else if newName == "" {
+		fieldErrors["nachname"] = "Diese Person ist bereits eingetragen"
 	}
+	vorname := values.Get("vorname")
+	results := make([]model.SectionResult, len(exam.Sections))
+	for i, section := range exam.Sections {
+		punkte, message := parsePunkte(locale, values.Get(punkteField(i)), section)
+		if message != "" {
+			fieldErrors[punkteField(i)] = message
+		}
+		results[i].Punkte = punkte
+	}
+	if len(fieldErrors) > 0 {
+		return model.Bewertung{}, fieldErrors
+	}
+
+	// Create a new Bewertung struct
+	return model.Bewerte(exam, notenschluessel, model.Bewertung{
+		ExamID:    exam.ID,
+		Vorname:   string(vorname),
+		Nachname:  string(newName),
+		Kommentar: values.Get("kommentar"),
+		Sections:  results,
+		Gewertet:  true,
+	}), nil
 }
-func createBewertungNode(bewertung Bewertung) elem.Node {
+
+func updateGewertetRoute(bewertung model.Bewertung) elem.Node {
 	checkbox := elem.Input(attrs.Props{
 		attrs.Type:    "checkbox",
 		attrs.Checked: strconv.FormatBool(bewertung.Gewertet),
 		htmx.HXPost:   "/toggle/" + strconv.Itoa(bewertung.ID),
 		htmx.HXTarget: "#bewertung-" + strconv.Itoa(bewertung.ID),
-		htmx.HXSwap:   "outerHTML",
 	})
-
-	return elem.Tr(attrs.Props{
-		attrs.ID: "bewertung-" + strconv.Itoa(bewertung.ID),
-	},
-		elem.Td(nil, checkbox),
-		elem.Td(nil, elem.Text(bewertung.Vorname)),
-		elem.Td(nil, elem.Text(bewertung.Nachname)),
-		elem.Td(nil, elem.Text(strconv.FormatFloat(bewertung.HvPunkte, 'f', 2, 64))),
-		elem.Td(nil, elem.Text(strconv.FormatFloat(bewertung.HvProzent, 'f', 2, 64))),
-		elem.Td(nil, elem.Text(strconv.Itoa(bewertung.HvNote))),
-		elem.Td(nil, elem.Text(strconv.FormatFloat(bewertung.LvPunkte, 'f', 2, 64))),
-		elem.Td(nil, elem.Text(strconv.FormatFloat(bewertung.LvProzent, 'f', 2, 64))),
-		elem.Td(nil, elem.Text(strconv.Itoa(bewertung.LvNote))),
-		elem.Td(nil, elem.Text(strconv.FormatFloat(bewertung.GesamtProzent, 'f', 2, 64))),
-		elem.Td(nil, elem.Text(strconv.Itoa(bewertung.GesamtNote))),
-	)
+	return checkbox
 }
 
-func renderBewertungen(bewertungen []Bewertung) string {
-	inputPunkte := elem.Div(nil)
-	if maxPunkte.HvGewichtung == 0.00 {
-		inputPunkte = elem.Div(attrs.Props{attrs.Class: "tile is-ancestor"},
-			elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
-				elem.Input(attrs.Props{
-					attrs.Class:       "input is-child",
-					attrs.Type:        "text",
-					attrs.Name:        "hv_max",
-					attrs.Placeholder: "HV-Max-Punkte",
-				},
-				),
-			),
-			elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
-				elem.Input(attrs.Props{
-					attrs.Class:       "input is-child",
-					attrs.Type:        "text",
-					attrs.Name:        "hv_gewichtung",
-					attrs.Placeholder: "HV-Gewichtung in %",
-				},
-				),
-			),
-			elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
-				elem.Input(attrs.Props{
-					attrs.Class:       "input is-child",
-					attrs.Type:        "text",
-					attrs.Name:        "lv_max",
-					attrs.Placeholder: "LV-Max-Punkte",
+func createBewertungNode(locale Locale, bewertung model.Bewertung) elem.Node {
+	checkboxProps := attrs.Props{
+		attrs.Type:    "checkbox",
+		attrs.Checked: strconv.FormatBool(bewertung.Gewertet),
+		htmx.HXPost:   "/toggle/" + strconv.Itoa(bewertung.ID),
+		htmx.HXTarget: "#bewertung-" + strconv.Itoa(bewertung.ID),
+		htmx.HXSwap:   "outerHTML",
+	}
+	if bewertung.Gewertet {
+		checkboxProps["hx-prompt"] = "Grund, z.B. krank, Täuschungsversuch oder Nachschreiber (optional)"
+	}
+
+	var ausschluss elem.Node = elem.None()
+	if !bewertung.Gewertet {
+		ausschluss = elem.Span(attrs.Props{attrs.Class: "tag is-warning is-light ml-2"}, elem.Text(html.EscapeString(ausschlussText(bewertung))))
+	}
+
+	cells := []elem.Node{
+		elem.Td(nil, elem.Input(checkboxProps)),
+		elem.Td(nil, elem.Text(html.EscapeString(bewertung.Vorname))),
+		elem.Td(nil, elem.Text(html.EscapeString(bewertung.Nachname)), ausschluss),
+	}
+	for _, result := range bewertung.Sections {
+		cells = append(cells,
+			elem.Td(nil, elem.Text(locale.FormatNumber(result.Punkte, 2))),
+			elem.Td(nil, elem.Text(locale.FormatNumber(result.Prozent, 2))),
+			elem.Td(nil, elem.Text(html.EscapeString(result.Note.Name))),
+		)
+	}
+	cells = append(cells,
+		elem.Td(nil, elem.Text(locale.FormatNumber(bewertung.GesamtProzent, 2))),
+		elem.Td(nil, elem.Text(html.EscapeString(bewertung.GesamtNote.Name))),
+		elem.Td(nil,
+			elem.Div(attrs.Props{attrs.Class: "buttons are-small"},
+				elem.Button(attrs.Props{
+					attrs.Class:   "button",
+					htmx.HXGet:    bewertungURL(bewertung) + "/edit",
+					htmx.HXTarget: "closest tr",
+					htmx.HXSwap:   "outerHTML",
 				},
+					elem.Text("Bearbeiten"),
 				),
-			),
-			elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
-				elem.Input(attrs.Props{
-					attrs.Class:       "input is-child",
-					attrs.Type:        "text",
-					attrs.Name:        "lv_gewichtung",
-					attrs.Placeholder: "LV-Gewichtung in %",
+				elem.Button(attrs.Props{
+					attrs.Class:    "button is-danger is-light",
+					htmx.HXDelete:  bewertungURL(bewertung),
+					htmx.HXConfirm: html.EscapeString("Bewertung von " + bewertung.Vorname + " " + bewertung.Nachname + " löschen?"),
+					htmx.HXTarget:  "closest tr",
+					htmx.HXSwap:    "outerHTML",
 				},
+					elem.Text("Löschen"),
 				),
 			),
+		),
+	)
+
+	rowProps := attrs.Props{attrs.ID: "bewertung-" + strconv.Itoa(bewertung.ID)}
+	if !bewertung.Gewertet {
+		rowProps[attrs.Class] = "has-text-grey-light"
+	}
+	return elem.Tr(rowProps, cells...)
+}
+
+// ausschlussText describes a Bewertung that is not gewertet.
+func ausschlussText(bewertung model.Bewertung) string {
+	if bewertung.Grund == "" {
+		return "nicht gewertet"
+	}
+	return "nicht gewertet: " + bewertung.Grund
+}
+
+// createBewertungEditNode renders the row of a Bewertung as inline form
+// filled with values. The inputs are sent by the save button via hx-include.
+func createBewertungEditNode(exam model.Exam, bewertung model.Bewertung, values url.Values, fieldErrors FieldErrors) elem.Node {
+	input := func(name, placeholder string) elem.Node {
+		return elem.Td(nil, createInputNode("input is-small", name, placeholder, values.Get(name), fieldErrors[name])...)
+	}
+
+	cells := []elem.Node{
+		elem.Td(nil, elem.Input(attrs.Props{
+			attrs.Type:     "checkbox",
+			attrs.Checked:  strconv.FormatBool(bewertung.Gewertet),
+			attrs.Disabled: "true",
+		})),
+		input("vorname", "Vorname"),
+		input("nachname", "Nachname"),
+	}
+	for i, section := range exam.Sections {
+		cells = append(cells,
+			input(punkteField(i), section.Name+"-Punkte"),
+			elem.Td(nil),
+			elem.Td(nil),
 		)
-	} else {
-		inputPunkte = elem.Div(attrs.Props{attrs.Class: "tile is-ancestor"},
-			elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
-				elem.Input(attrs.Props{
-					attrs.Class:       "input is-child",
-					attrs.Type:        "text",
-					attrs.Name:        "hv_max",
-					attrs.Placeholder: "HV-Max-Punkte",
-					attrs.Value:       fmt.Sprintf("%.2f", maxPunkte.HvMax),
-				},
-				),
-			),
-			elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
-				elem.Input(attrs.Props{
-					attrs.Class:       "input is-child",
-					attrs.Type:        "text",
-					attrs.Name:        "hv_gewichtung",
-					attrs.Placeholder: "HV-Gewichtung in %",
-					attrs.Value:       fmt.Sprintf("%.2f", maxPunkte.HvGewichtung),
+	}
+	cells = append(cells,
+		elem.Td(attrs.Props{"colspan": "2"},
+			elem.Textarea(attrs.Props{
+				attrs.Name:        "kommentar",
+				attrs.Class:       "textarea is-small",
+				attrs.Rows:        "2",
+				attrs.Placeholder: "Kommentar für den Rückmeldebogen",
+			}, elem.Text(html.EscapeString(values.Get("kommentar")))),
+		),
+		elem.Td(nil,
+			elem.Div(attrs.Props{attrs.Class: "buttons are-small"},
+				elem.Button(attrs.Props{
+					attrs.Class:   "button is-primary",
+					htmx.HXPut:    bewertungURL(bewertung),
+					"hx-include":  "closest tr",
+					htmx.HXTarget: "closest tr",
+					htmx.HXSwap:   "outerHTML",
 				},
+					elem.Text("Speichern"),
 				),
-			),
-			elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
-				elem.Input(attrs.Props{
-					attrs.Class:       "input is-child",
-					attrs.Type:        "text",
-					attrs.Name:        "lv_max",
-					attrs.Placeholder: "LV-Max-Punkte",
-					attrs.Value:       fmt.Sprintf("%.2f", maxPunkte.LvMax),
+				elem.Button(attrs.Props{
+					attrs.Class:   "button",
+					htmx.HXGet:    bewertungURL(bewertung),
+					htmx.HXTarget: "closest tr",
+					htmx.HXSwap:   "outerHTML",
 				},
+					elem.Text("Abbrechen"),
 				),
 			),
-			elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
-				elem.Input(attrs.Props{
-					attrs.Class:       "input is-child",
-					attrs.Type:        "text",
-					attrs.Name:        "lv_gewichtung",
-					attrs.Placeholder: "LV-Gewichtung in %",
-					attrs.Value:       fmt.Sprintf("%.2f", maxPunkte.LvGewichtung),
+		),
+	)
+
+	return elem.Tr(attrs.Props{
+		attrs.ID: "bewertung-" + strconv.Itoa(bewertung.ID),
+	}, cells...)
+}
+
+// createBewertungFormNode renders the form for adding a Bewertung, filled
+// with values and showing fieldErrors next to the inputs. htmx replaces the
+// form with the response when validation fails.
+func createBewertungFormNode(exam model.Exam, values url.Values, fieldErrors FieldErrors) elem.Node {
+	field := func(name, placeholder string) elem.Node {
+		return elem.Div(attrs.Props{attrs.Class: "tile field is-parent is-vertical"},
+			createInputNode("input is-child", name, placeholder, values.Get(name), fieldErrors[name])...,
+		)
+	}
+
+	inputFields := []elem.Node{
+		field("vorname", "Vorname"),
+		field("nachname", "Nachname"),
+	}
+	for i, section := range exam.Sections {
+		inputFields = append(inputFields, field(punkteField(i), section.Name+"-Punkte"))
+	}
+	inputFields = append(inputFields,
+		elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
+			elem.Button(
+				attrs.Props{
+					attrs.Type:  "submit",
+					attrs.Class: "button tile is-child",
 				},
-				),
+				elem.Text("Add"),
 			),
+		),
+	)
+
+	return elem.Form(attrs.Props{
+		attrs.Method:  "post",
+		attrs.Action:  examURL(exam) + "/add",
+		htmx.HXPost:   examURL(exam) + "/add",
+		htmx.HXTarget: "this",
+		htmx.HXSwap:   "outerHTML",
+	},
+		elem.Div(attrs.Props{attrs.Class: "tile is-ancestor"}, inputFields...),
+	)
+}
+
+// renderBewertungen renders the page of an exam with the given form for
+// adding a Bewertung. The Ansicht only applies to the table, the statistics
+// always cover every Bewertung.
+func renderBewertungen(locale Locale, exam model.Exam, notenschluessel model.Notenschluessel, bewertungen []model.Bewertung, ansicht Ansicht, form elem.Node) string {
+	headerCells := []elem.Node{
+		elem.Th(nil, elem.Text("Gewertet")),
+		elem.Th(nil, elem.Text("Vorname")),
+		elem.Th(nil, elem.Text("Nachname")),
+	}
+	for _, section := range exam.Sections {
+		headerCells = append(headerCells,
+			elem.Th(nil, elem.Text(html.EscapeString(section.Name+"-Punkte"))),
+			elem.Th(nil, elem.Text(html.EscapeString(section.Name+"-Prozent"))),
+			elem.Th(nil, elem.Text(html.EscapeString(section.Name+"-Note"))),
 		)
 	}
+	headerCells = append(headerCells,
+		elem.Th(nil, elem.Text("Gesamt-Prozent")),
+		elem.Th(nil, elem.Text("Gesamt-Note")),
+		elem.Th(nil),
+	)
 
+	bodyContent := elem.Div(attrs.Props{attrs.Class: "container is-widescreen"},
+		elem.Div(attrs.Props{attrs.Class: "card tile is-vertical is-ancestor"},
+			elem.Header(attrs.Props{attrs.Class: "card-header"},
+				elem.P(attrs.Props{attrs.Class: "card-header-title"}, elem.Text(html.EscapeString(exam.Titel)))),
+			elem.Div(attrs.Props{attrs.Class: "card-content"},
+				elem.Div(attrs.Props{attrs.Class: "content tile is-parent is-vertical gap"},
+					elem.H1(attrs.Props{attrs.Class: "tilte"}, elem.Text("Bewertungen")),
+					elem.Div(attrs.Props{attrs.Class: "level"},
+						elem.Div(attrs.Props{attrs.Class: "level-left"},
+							createSectionsSummaryNode(locale, exam.Sections, notenschluessel),
+						),
+						elem.Div(attrs.Props{attrs.Class: "level-right"},
+							elem.A(attrs.Props{
+								attrs.Class: "button is-small",
+								attrs.Href:  examURL(exam) + "/settings",
+							},
+								elem.Text("Einstellungen"),
+							),
+						),
+					),
+					form,
+					createAnsichtFormNode(exam, ansicht),
+					elem.Div(attrs.Props{attrs.Class: "table-container"},
+						elem.Table(attrs.Props{attrs.Class: "table is-hoverable"},
+							elem.THead(nil,
+								elem.Tr(nil, headerCells...),
+							),
+							createBewertungenBodyNode(locale, exam, ansicht.Anwenden(notenschluessel, bewertungen)),
+						),
+					),
+					createStatistikNode(locale, exam, berechneStatistik(exam, notenschluessel, bewertungen)),
+					createImportFormNode(exam),
+					elem.Div(attrs.Props{attrs.Class: "buttons"},
+						createExportLinkNode(exam, "format=pdf", "PDF"),
+						createExportLinkNode(exam, "format=pdf&ausgeschlossen=1", "PDF mit Nichtgewerteten"),
+						createExportLinkNode(exam, "format=csv", "CSV"),
+						createExportLinkNode(exam, "format=csv&ausgeschlossen=1", "CSV mit Nichtgewerteten"),
+						createExportLinkNode(exam, "format=xlsx", "Excel"),
+						createExportLinkNode(exam, "format=xlsx&ausgeschlossen=1", "Excel mit Nichtgewerteten"),
+						createRueckmeldeboegenLinkNode(exam, "pdf", "Rückmeldebögen"),
+						createRueckmeldeboegenLinkNode(exam, "zip", "Rückmeldebögen als ZIP"),
+					),
+				),
+			),
+		),
+	)
+
+	return renderPage(bodyContent)
+}
+
+// renderPage wraps the content with the head, navigation and footer shared
+// by all pages.
+func renderPage(bodyContent elem.Node) string {
 	headContent := elem.Head(nil,
 		elem.Meta(attrs.Props{attrs.Charset: "UTF-8", attrs.Name: "viewport", attrs.Content: "width=device-width, initial-scale=1.0"}),
-		elem.Script(attrs.Props{attrs.Src: "https://unpkg.com/htmx.org"}),
-		elem.Link(attrs.Props{attrs.Rel: "stylesheet", attrs.Href: "https://cdn.jsdelivr.net/npm/bulma@0.9.4/css/bulma.min.css"}),
+		elem.Script(attrs.Props{attrs.Src: "/assets/htmx-1.9.12.min.js"}),
+		elem.Link(attrs.Props{attrs.Rel: "stylesheet", attrs.Href: "/assets/bulma-0.9.4.min.css"}),
 	)
 
 	headerContent := elem.Header(attrs.Props{
@@ -215,19 +495,41 @@ func renderBewertungen(bewertungen []Bewertung) string {
 			},
 				elem.A(attrs.Props{
 					attrs.Class: "navbar-item",
+					attrs.Href:  "/exams",
 				}, elem.Text("Home"),
 				),
+				elem.A(attrs.Props{
+					attrs.Class: "navbar-item",
+					attrs.Href:  "/scales",
+				}, elem.Text("Notenschlüssel"),
+				),
+				elem.A(attrs.Props{
+					attrs.Class: "navbar-item",
+					attrs.Href:  "/konten",
+				}, elem.Text("Konten"),
+				),
 			),
 			elem.Div(attrs.Props{
 				attrs.Class: "navbar-end",
 			},
+				elem.Span(attrs.Props{
+					attrs.Class: "navbar-item",
+				},
+					elem.Button(attrs.Props{
+						attrs.Class:    "button",
+						htmx.HXTrigger: "click",
+						htmx.HXPost:    "/logout",
+					}, elem.Text("Abmelden"),
+					),
+				),
 				elem.Span(attrs.Props{
 					attrs.Class: "navbar-item",
 				},
 					elem.Button(attrs.Props{
 						attrs.Class:    "button is-primary",
 						htmx.HXTrigger: "click",
-						htmx.HXGet:     "/end",
+						htmx.HXPost:    "/end",
+						htmx.HXTarget:  "body",
 					}, elem.Text("Beenden"),
 					),
 				),
@@ -235,96 +537,6 @@ func renderBewertungen(bewertungen []Bewertung) string {
 		),
 	)
 
-	bodyContent := elem.Div(attrs.Props{attrs.Class: "container is-widescreen"},
-		elem.Div(attrs.Props{attrs.Class: "card tile is-vertical is-ancestor"},
-			elem.Header(attrs.Props{attrs.Class: "card-header"},
-				elem.P(attrs.Props{attrs.Class: "card-header-title"}, elem.Text("Englischarbeit"))),
-			elem.Div(attrs.Props{attrs.Class: "card-content"},
-				elem.Div(attrs.Props{attrs.Class: "content tile is-parent is-vertical gap"},
-					elem.H1(attrs.Props{attrs.Class: "tilte"}, elem.Text("Bewertungen")),
-					elem.Form(attrs.Props{attrs.Method: "post", attrs.Action: "/add"}, inputPunkte,
-						elem.Div(attrs.Props{attrs.Class: "tile is-ancestor"},
-							elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
-								elem.Input(attrs.Props{
-									attrs.Type:        "text",
-									attrs.Name:        "vorname",
-									attrs.Class:       "input is-child",
-									attrs.Placeholder: "Vorname",
-								},
-								),
-							),
-							elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
-								elem.Input(attrs.Props{
-									attrs.Type:        "text",
-									attrs.Name:        "nachname",
-									attrs.Class:       "input is-child",
-									attrs.Placeholder: "Nachname",
-								},
-								),
-							),
-							elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
-								elem.Input(attrs.Props{
-									attrs.Type:        "text",
-									attrs.Name:        "hv_punkte",
-									attrs.Class:       "input is-child",
-									attrs.Placeholder: "HV-Punkte",
-								},
-								),
-							),
-							elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
-								elem.Input(attrs.Props{
-									attrs.Type:        "text",
-									attrs.Name:        "lv_punkte",
-									attrs.Class:       "input is-child",
-									attrs.Placeholder: "LV-Punkte",
-								},
-								),
-							),
-							elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
-								elem.Button(
-									attrs.Props{
-										attrs.Type:  "submit",
-										attrs.Class: "button tile is-child",
-									},
-									elem.Text("Add"),
-								),
-							),
-						),
-					),
-					elem.Div(attrs.Props{attrs.Class: "table-container"},
-						elem.Table(attrs.Props{attrs.Class: "table is-hoverable"},
-							elem.THead(nil,
-								elem.Tr(nil,
-									elem.Th(nil, elem.Text("Gewertet")),
-									elem.Th(nil, elem.Text("Vorname")),
-									elem.Th(nil, elem.Text("Nachname")),
-									elem.Th(nil, elem.Text("HV-Punkte")),
-									elem.Th(nil, elem.Text("HV-Prozent")),
-									elem.Th(nil, elem.Text("HV-Note")),
-									elem.Th(nil, elem.Text("LV-Punkte")),
-									elem.Th(nil, elem.Text("LV-Prozent")),
-									elem.Th(nil, elem.Text("LV-Note")),
-									elem.Th(nil, elem.Text("Gesamt-Prozent")),
-									elem.Th(nil, elem.Text("Gesamt-Note")),
-								),
-							),
-							elem.TBody(nil,
-								elem.TransformEach(bewertungen, createBewertungNode)...),
-						),
-					),
-					elem.Div(nil,
-						elem.Button(attrs.Props{
-							htmx.HXTrigger: "click",
-							htmx.HXGet:     "/export",
-							attrs.Class:    "button",
-						},
-							elem.Text("export"),
-						),
-					),
-				),
-			),
-		),
-	)
 	footerContent := elem.Footer(attrs.Props{
 		attrs.Class: "footer",
 	},
@@ -340,3 +552,49 @@ func renderBewertungen(bewertungen []Bewertung) string {
 
 	return htmlContent.Render()
 }
+
+func checkGewichtung(sections []model.Section) bool {
+	var gewichtung float64
+	for _, section := range sections {
+		gewichtung += section.Gewichtung
+	}
+	return math.Abs(gewichtung-100) < 0.001
+}
+
+// validateName returns the Nachname from values, or an empty string if
+// another Bewertung than the one with the given id has the same name.
+func validateName(values url.Values, bewertungen []model.Bewertung, id int) string {
+	newNachname := values.Get("nachname")
+	newVorname := values.Get("vorname")
+	for _, bewertung := range bewertungen {
+		if bewertung.ID != id && bewertung.Nachname == newNachname && bewertung.Vorname == newVorname {
+			return ""
+		}
+	}
+	return newNachname
+}
+
+// endRoute says goodbye and then calls Options.Beenden, which must not
+// wait for this response to be sent.
+func (h *Controller) endRoute(c echo.Context) error {
+	abschied := createAbschiedNode()
+	var err error
+	if isHTMX(c) {
+		err = c.HTML(http.StatusOK, abschied.Render())
+	} else {
+		err = c.HTML(http.StatusOK, renderPage(abschied))
+	}
+	if h.beenden != nil {
+		h.beenden()
+	}
+	return err
+}
+
+func createAbschiedNode() elem.Node {
+	return elem.Section(attrs.Props{attrs.Class: "section"},
+		elem.Div(attrs.Props{attrs.Class: "container has-text-centered"},
+			elem.H1(attrs.Props{attrs.Class: "title"}, elem.Text("Tschüss")),
+			elem.P(nil, elem.Text("Alle Änderungen sind gespeichert. Das Fenster kann jetzt geschlossen werden.")),
+		),
+	)
+}
@@ -0,0 +1,309 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"echoTest/model"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// testController serves the tests from a store in a temporary directory.
+var testController *Controller
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "echotest")
+	if err != nil {
+		panic(err)
+	}
+	jsonStore, err := model.NewJSONStore(filepath.Join(dir, "bewertungen.json"))
+	if err != nil {
+		panic(err)
+	}
+	testController = New(Options{Store: jsonStore})
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// createTestExam stores an exam that is removed again when the test ends.
+func createTestExam(t *testing.T) model.Exam {
+	exam, err := testController.store.SaveExam(model.Exam{
+		Titel: "Englischarbeit",
+		Sections: []model.Section{
+			{Name: "HV", Max: 20, Gewichtung: 50},
+			{Name: "LV", Max: 30, Gewichtung: 50},
+		},
+	})
+	assert.NoError(t, err)
+	t.Cleanup(func() { testController.store.DeleteExam(exam.ID) })
+	return exam
+}
+
+func TestRenderExamsRoute(t *testing.T) {
+	createTestExam(t)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := testController.renderExamsRoute(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Englischarbeit")
+}
+
+func TestAddExamRouteValidation(t *testing.T) {
+	form := url.Values{
+		"titel":              {""},
+		"fach":               {"Englisch"},
+		"section_name":       {"HV", "LV"},
+		"section_max":        {"20", "30"},
+		"section_gewichtung": {"50", "40"},
+	}
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/exams", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+
+	assert.NoError(t, testController.addExamRoute(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Bitte einen Titel eingeben")
+	assert.Contains(t, rec.Body.String(), "Die Gewichtungen müssen zusammen 100 % ergeben")
+	assert.Contains(t, rec.Body.String(), `value="Englisch"`)
+	exams, err := testController.store.ListExams()
+	assert.NoError(t, err)
+	for _, exam := range exams {
+		assert.NotEqual(t, "Englisch", exam.Fach)
+	}
+}
+
+func TestRenderBewertungenRoute(t *testing.T) {
+	exam := createTestExam(t)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, examURL(exam), nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(exam.ID))
+
+	err := testController.renderBewertungenRoute(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestToggleWertungRoute(t *testing.T) {
+	exam := createTestExam(t)
+	saved, err := testController.store.Save(model.Bewertung{ExamID: exam.ID, Nachname: "Muster", Gewertet: true})
+	assert.NoError(t, err)
+	id := strconv.Itoa(saved.ID)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/toggle/"+id, nil)
+	req.Header.Set("HX-Prompt", "krank")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+
+	err = testController.toggleWertungRoute(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Contains(t, rec.Body.String(), "nicht gewertet: krank")
+	assert.Equal(t, statistikChanged, rec.Header().Get("HX-Trigger"))
+
+	bewertung, err := testController.store.Load(saved.ID)
+	assert.NoError(t, err)
+	assert.False(t, bewertung.Gewertet)
+	assert.Equal(t, "krank", bewertung.Grund)
+}
+
+func TestUpdateBewertungRoute(t *testing.T) {
+	exam := createTestExam(t)
+	saved, err := testController.store.Save(model.Bewertung{ExamID: exam.ID, Vorname: "Anna", Nachname: "Muster", Gewertet: true})
+	assert.NoError(t, err)
+	id := strconv.Itoa(saved.ID)
+
+	e := echo.New()
+	form := url.Values{"vorname": {"Anna"}, "nachname": {"Muster"}, "punkte_0": {"19"}, "punkte_1": {"15"}, "kommentar": {"Gut gemacht"}}
+	req := httptest.NewRequest(http.MethodPut, "/bewertung/"+id, strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+
+	err = testController.updateBewertungRoute(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `id="bewertung-`+id+`"`)
+
+	bewertung, err := testController.store.Load(saved.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 95.0, bewertung.Sections[0].Prozent)
+	assert.Equal(t, 50.0, bewertung.Sections[1].Prozent)
+	assert.Equal(t, "3", bewertung.GesamtNote.Name)
+	assert.Equal(t, "Gut gemacht", bewertung.Kommentar)
+	assert.True(t, bewertung.Gewertet)
+}
+
+func TestDeleteBewertungRoute(t *testing.T) {
+	exam := createTestExam(t)
+	saved, err := testController.store.Save(model.Bewertung{ExamID: exam.ID, Nachname: "Muster"})
+	assert.NoError(t, err)
+	id := strconv.Itoa(saved.ID)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/bewertung/"+id, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+
+	err = testController.deleteBewertungRoute(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	_, err = testController.store.Load(saved.ID)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestUpdateExamSettingsRoute(t *testing.T) {
+	exam := createTestExam(t)
+	saved, err := testController.store.Save(model.Bewerte(exam, model.StandardNotenschluessel, model.Bewertung{
+		ExamID:   exam.ID,
+		Nachname: "Muster",
+		Sections: []model.SectionResult{{Punkte: 10}, {Punkte: 15}},
+	}))
+	assert.NoError(t, err)
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, examURL(exam)+"/settings", strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(strconv.Itoa(exam.ID))
+		assert.NoError(t, testController.updateExamSettingsRoute(c))
+		return rec
+	}
+
+	rec := post(url.Values{
+		"section_index":      {"0", "1"},
+		"section_name":       {"HV", "LV"},
+		"section_max":        {"20", "30"},
+		"section_gewichtung": {"50", "60"},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "100 %")
+
+	// Drop HV and give LV the full weight with fewer max points
+	rec = post(url.Values{
+		"section_index":      {"0", "1"},
+		"section_name":       {"", "LV"},
+		"section_max":        {"20", "20"},
+		"section_gewichtung": {"50", "100"},
+	})
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+
+	exam, err = testController.store.LoadExam(exam.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Section{{Name: "LV", Max: 20, Gewichtung: 100}}, exam.Sections)
+	bewertung, err := testController.store.Load(saved.ID)
+	assert.NoError(t, err)
+	assert.Len(t, bewertung.Sections, 1)
+	assert.Equal(t, 15.0, bewertung.Sections[0].Punkte)
+	assert.Equal(t, 75.0, bewertung.GesamtProzent)
+	assert.Equal(t, "3", bewertung.GesamtNote.Name)
+}
+
+func TestAddBewertungRouteValidation(t *testing.T) {
+	exam := createTestExam(t)
+
+	post := func(form url.Values, htmx bool) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, examURL(exam)+"/add", strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		if htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(strconv.Itoa(exam.ID))
+		assert.NoError(t, testController.addBewertungRoute(c))
+		return rec
+	}
+
+	rec := post(url.Values{"vorname": {"Anna"}, "nachname": {"Muster"}, "punkte_0": {"12,5x"}, "punkte_1": {"31"}}, true)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Keine gültige Zahl")
+	assert.Contains(t, rec.Body.String(), "Höchstens 30,00 Punkte")
+	assert.Contains(t, rec.Body.String(), `value="Anna"`)
+	assert.NotContains(t, rec.Body.String(), "<html>")
+
+	rec = post(url.Values{"vorname": {"Anna"}, "punkte_0": {"10"}, "punkte_1": {"20"}}, false)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Bitte einen Nachnamen eingeben")
+
+	rec = post(url.Values{"vorname": {"Anna"}, "nachname": {"Muster"}, "punkte_0": {"10"}, "punkte_1": {"20"}}, true)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, examURL(exam), rec.Header().Get("HX-Redirect"))
+
+	rec = post(url.Values{"vorname": {"Anna"}, "nachname": {"Muster"}, "punkte_0": {"10"}, "punkte_1": {"20"}}, true)
+	assert.Contains(t, rec.Body.String(), "Diese Person ist bereits eingetragen")
+
+	bewertungen, err := testController.store.List(exam.ID)
+	assert.NoError(t, err)
+	assert.Len(t, bewertungen, 1)
+}
+
+func TestBewertungEscaping(t *testing.T) {
+	exam := createTestExam(t)
+	bewertung := model.Bewertung{
+		ExamID:    exam.ID,
+		Vorname:   `Anna" onmouseover="alert(1)`,
+		Nachname:  "<script>alert(1)</script>",
+		Kommentar: "</textarea><b>fett</b>",
+	}
+
+	row := createBewertungNode(localeDE, bewertung).Render()
+	assert.NotContains(t, row, "<script>")
+	assert.NotContains(t, row, `" onmouseover="`)
+	assert.Contains(t, row, "&lt;script&gt;")
+
+	edit := createBewertungEditNode(exam, bewertung, bewertungValues(localeDE, bewertung), nil).Render()
+	assert.NotContains(t, edit, "</textarea><b>")
+	assert.Contains(t, edit, "&lt;/textarea&gt;&lt;b&gt;fett&lt;/b&gt;")
+	assert.Contains(t, edit, `value="Anna&#34; onmouseover=&#34;alert(1)"`)
+}
+
+func TestEndRoute(t *testing.T) {
+	beendet := false
+	h := New(Options{Store: testController.store, Beenden: func() { beendet = true }})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/end", nil)
+	req.Header.Set("HX-Request", "true")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	assert.NoError(t, h.endRoute(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Tschüss")
+	assert.NotContains(t, rec.Body.String(), "<html")
+	assert.True(t, beendet)
+}
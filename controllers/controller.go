@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"time"
+
+	"echoTest/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Options are the dependencies of a Controller.
+type Options struct {
+	Store model.Store
+	// Notenschluessel is preselected for new exams and used for exams whose
+	// scale was deleted. It defaults to model.StandardNotenschluessel.
+	Notenschluessel model.Notenschluessel
+	// Now returns the current time and defaults to time.Now.
+	Now func() time.Time
+	// ExportArchive is a directory that keeps a copy of every export,
+	// empty disables the archive.
+	ExportArchive string
+	// Beenden is called when the user ends the application.
+	Beenden func()
+	// Locale formats the numbers on pages and in exports and defaults to
+	// German, see LocaleByName.
+	Locale Locale
+}
+
+// Controller serves the grading pages, the exports and the JSON API.
+type Controller struct {
+	store           model.Store
+	notenschluessel model.Notenschluessel
+	now             func() time.Time
+	exportArchive   string
+	beenden         func()
+	locale          Locale
+	sitzungen       *sitzungen
+}
+
+// New returns a Controller using the given dependencies.
+func New(options Options) *Controller {
+	h := &Controller{
+		store:           options.Store,
+		notenschluessel: options.Notenschluessel,
+		now:             options.Now,
+		exportArchive:   options.ExportArchive,
+		beenden:         options.Beenden,
+		locale:          options.Locale,
+		sitzungen:       newSitzungen(),
+	}
+	if h.notenschluessel.ID == 0 {
+		h.notenschluessel = model.StandardNotenschluessel
+	}
+	if h.now == nil {
+		h.now = time.Now
+	}
+	if h.locale == (Locale{}) {
+		h.locale = localeDE
+	}
+	return h
+}
+
+// Register adds the routes of the Controller to e. Except for the login
+// pages and the assets, all of them require a logged-in Konto.
+func (h *Controller) Register(e *echo.Echo) {
+	registerAssets(e)
+	e.GET("/login", h.renderLoginRoute)
+	e.POST("/login", h.loginRoute)
+	e.POST("/logout", h.logoutRoute)
+	e.GET("/setup", h.renderSetupRoute)
+	e.POST("/setup", h.setupRoute)
+
+	g := e.Group("", h.requireLogin)
+	h.registerAPI(g)
+	g.GET("/", h.renderExamsRoute)
+	g.GET("/exams", h.renderExamsRoute)
+	g.POST("/exams", h.addExamRoute)
+	g.GET("/exams/section", h.sectionInputRoute)
+	g.GET("/exams/:id", h.renderBewertungenRoute)
+	g.POST("/exams/:id/add", h.addBewertungRoute)
+	g.POST("/exams/:id/import", h.importBewertungenRoute)
+	g.GET("/exams/:id/settings", h.renderExamSettingsRoute)
+	g.POST("/exams/:id/settings", h.updateExamSettingsRoute)
+	g.GET("/exams/:id/statistik", h.renderStatistikRoute)
+	g.GET("/exams/:id/export", h.exportBewertungenRoute)
+	g.GET("/exams/:id/export/students", h.exportRueckmeldeboegenRoute)
+	g.POST("/toggle/:id", h.toggleWertungRoute)
+	g.GET("/bewertung/:id", h.renderBewertungRoute)
+	g.GET("/bewertung/:id/edit", h.editBewertungRoute)
+	g.PUT("/bewertung/:id", h.updateBewertungRoute)
+	g.DELETE("/bewertung/:id", h.deleteBewertungRoute)
+	g.GET("/scales", h.renderNotenschluesselListRoute)
+	g.POST("/scales", h.addNotenschluesselRoute)
+	g.GET("/scales/stufe", h.notenstufeInputRoute)
+	g.GET("/scales/:id", h.renderNotenschluesselRoute)
+	g.POST("/scales/:id", h.updateNotenschluesselRoute)
+	g.DELETE("/scales/:id", h.deleteNotenschluesselRoute)
+	g.POST("/end", h.endRoute)
+	g.GET("/konten", h.renderKontenRoute)
+	g.POST("/konten", h.addKontoRoute)
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"html"
+	"strconv"
+
+	"github.com/chasefleming/elem-go"
+	"github.com/chasefleming/elem-go/attrs"
+)
+
+// Diagramm is a bar chart counting how often each label occurs.
+type Diagramm struct {
+	Titel  string
+	Balken []Balken
+}
+
+// Balken is one bar of a Diagramm.
+type Balken struct {
+	Label  string
+	Anzahl int
+}
+
+// hoechsteAnzahl returns the highest count, at least 1 so empty charts can
+// be scaled.
+func (d Diagramm) hoechsteAnzahl() int {
+	hoechste := 1
+	for _, balken := range d.Balken {
+		hoechste = max(hoechste, balken.Anzahl)
+	}
+	return hoechste
+}
+
+// prozentBereiche are the labels of the bins of TeilStatistik.Verteilung.
+var prozentBereiche = []string{"0", "10", "20", "30", "40", "50", "60", "70", "80", "90"}
+
+// diagramme returns a histogram of the Gesamt-Noten and one of the
+// percentages reached in every section.
+func diagramme(statistik Statistik) []Diagramm {
+	noten := Diagramm{Titel: "Gesamtnoten"}
+	for _, haeufigkeit := range statistik.Notenspiegel {
+		noten.Balken = append(noten.Balken, Balken{Label: haeufigkeit.Note.Name, Anzahl: haeufigkeit.Anzahl})
+	}
+	result := []Diagramm{noten}
+	for _, teil := range statistik.Teile {
+		diagramm := Diagramm{Titel: teil.Name + " in Prozent"}
+		for i, anzahl := range teil.Verteilung {
+			diagramm.Balken = append(diagramm.Balken, Balken{Label: prozentBereiche[i], Anzahl: anzahl})
+		}
+		result = append(result, diagramm)
+	}
+	return result
+}
+
+const (
+	svgWidth  = 320
+	svgHeight = 180
+	// svgMargin leaves room for the title above and the labels below.
+	svgMargin = 24
+)
+
+// createDiagrammNode renders the Diagramm as inline SVG.
+func createDiagrammNode(diagramm Diagramm) elem.Node {
+	svg := func(tag string, props attrs.Props, children ...elem.Node) elem.Node {
+		return elem.NewElement(tag, props, children...)
+	}
+	number := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', 1, 64)
+	}
+
+	hoechste := diagramm.hoechsteAnzahl()
+	plotHeight := float64(svgHeight - 2*svgMargin)
+	step := float64(svgWidth-2*svgMargin) / float64(max(len(diagramm.Balken), 1))
+	baseline := float64(svgHeight - svgMargin)
+
+	children := []elem.Node{
+		svg("text", attrs.Props{"x": number(svgWidth / 2), "y": "16", "text-anchor": "middle", "font-size": "13", "font-weight": "bold"},
+			elem.Text(html.EscapeString(diagramm.Titel))),
+		svg("line", attrs.Props{"x1": number(svgMargin), "y1": number(baseline), "x2": number(svgWidth - svgMargin), "y2": number(baseline), "stroke": "#4a4a4a"}),
+	}
+	for i, balken := range diagramm.Balken {
+		height := plotHeight * float64(balken.Anzahl) / float64(hoechste)
+		x := svgMargin + float64(i)*step
+		center := x + step/2
+		children = append(children,
+			svg("rect", attrs.Props{"x": number(x + step*0.1), "y": number(baseline - height), "width": number(step * 0.8), "height": number(height), "fill": "#485fc7"},
+				svg("title", nil, elem.Text(html.EscapeString(balken.Label)+": "+strconv.Itoa(balken.Anzahl)))),
+			svg("text", attrs.Props{"x": number(center), "y": number(baseline + 14), "text-anchor": "middle", "font-size": "10"},
+				elem.Text(html.EscapeString(balken.Label))),
+		)
+		if balken.Anzahl > 0 {
+			children = append(children,
+				svg("text", attrs.Props{"x": number(center), "y": number(baseline - height - 3), "text-anchor": "middle", "font-size": "10"},
+					elem.Text(strconv.Itoa(balken.Anzahl))),
+			)
+		}
+	}
+
+	return svg("svg", attrs.Props{
+		"xmlns":      "http://www.w3.org/2000/svg",
+		"viewBox":    "0 0 " + strconv.Itoa(svgWidth) + " " + strconv.Itoa(svgHeight),
+		"role":       "img",
+		"aria-label": html.EscapeString(diagramm.Titel),
+	}, children...)
+}
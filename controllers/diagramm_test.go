@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateDiagrammNode(t *testing.T) {
+	svg := createDiagrammNode(Diagramm{Titel: "HV <Teil>", Balken: []Balken{{Label: "1", Anzahl: 2}, {Label: "2", Anzahl: 0}}}).Render()
+	assert.Contains(t, svg, `<svg `)
+	assert.Contains(t, svg, "HV &lt;Teil&gt;")
+	assert.Contains(t, svg, `<rect fill="#485fc7" height="132.0"`)
+	assert.Contains(t, svg, `height="0.0"`)
+}
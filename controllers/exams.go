@@ -0,0 +1,334 @@
+package controllers
+
+import (
+	"errors"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"echoTest/model"
+
+	"github.com/chasefleming/elem-go"
+	"github.com/chasefleming/elem-go/attrs"
+	"github.com/chasefleming/elem-go/htmx"
+	"github.com/labstack/echo/v4"
+)
+
+const datumLayout = "2006-01-02"
+
+func (h *Controller) renderExamsRoute(c echo.Context) error {
+	exams, err := h.store.ListExams()
+	if err != nil {
+		return err
+	}
+	list, err := h.store.ListNotenschluessel()
+	if err != nil {
+		return err
+	}
+	form := model.Exam{
+		Sections:          []model.Section{{Name: "HV"}, {Name: "LV"}},
+		NotenschluesselID: h.notenschluessel.ID,
+	}
+	return c.HTML(http.StatusOK, h.renderExams(exams, list, form, nil))
+}
+
+func (h *Controller) addExamRoute(c echo.Context) error {
+	exam := model.Exam{
+		Titel:     c.FormValue("titel"),
+		Fach:      c.FormValue("fach"),
+		Klasse:    c.FormValue("klasse"),
+		Lehrkraft: c.FormValue("lehrkraft"),
+	}
+	exam.Sections, _ = parseSections(c)
+	exam.NotenschluesselID, _ = strconv.Atoi(c.FormValue("notenschluessel"))
+	if exam.NotenschluesselID == 0 {
+		exam.NotenschluesselID = h.notenschluessel.ID
+	}
+
+	fieldErrors := FieldErrors{}
+	if strings.TrimSpace(exam.Titel) == "" {
+		fieldErrors["titel"] = "Bitte einen Titel eingeben"
+	}
+	if message := validateSections(exam.Sections); message != "" {
+		fieldErrors["sections"] = message
+	}
+	if datum := c.FormValue("datum"); datum != "" {
+		parsed, err := time.Parse(datumLayout, datum)
+		if err != nil {
+			fieldErrors["datum"] = "Ungültiges Datum"
+		}
+		exam.Datum = parsed
+	}
+	if len(fieldErrors) > 0 {
+		exams, err := h.store.ListExams()
+		if err != nil {
+			return err
+		}
+		list, err := h.store.ListNotenschluessel()
+		if err != nil {
+			return err
+		}
+		return c.HTML(http.StatusUnprocessableEntity, h.renderExams(exams, list, exam, fieldErrors))
+	}
+
+	exam, err := h.store.SaveExam(exam)
+	if err != nil {
+		return err
+	}
+	return c.Redirect(http.StatusSeeOther, examURL(exam))
+}
+
+// loadExam returns the exam addressed by the :id route parameter.
+func (h *Controller) loadExam(c echo.Context) (model.Exam, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return model.Exam{}, echo.NewHTTPError(http.StatusNotFound, "Klassenarbeit nicht gefunden")
+	}
+	exam, err := h.store.LoadExam(id)
+	if errors.Is(err, model.ErrNotFound) {
+		return model.Exam{}, echo.NewHTTPError(http.StatusNotFound, "Klassenarbeit nicht gefunden")
+	}
+	return exam, err
+}
+
+func (h *Controller) renderExamSettingsRoute(c echo.Context) error {
+	exam, err := h.loadExam(c)
+	if err != nil {
+		return err
+	}
+	list, err := h.store.ListNotenschluessel()
+	if err != nil {
+		return err
+	}
+	return c.HTML(http.StatusOK, renderExamSettings(h.locale, exam, list, ""))
+}
+
+// updateExamSettingsRoute changes the sections and the grading scale of an
+// exam and recalculates all of its Bewertungen.
+func (h *Controller) updateExamSettingsRoute(c echo.Context) error {
+	exam, err := h.loadExam(c)
+	if err != nil {
+		return err
+	}
+	list, err := h.store.ListNotenschluessel()
+	if err != nil {
+		return err
+	}
+	sections, origins := parseSections(c)
+	if message := validateSections(sections); message != "" {
+		edited := exam
+		edited.Sections = sections
+		edited.Lehrkraft = c.FormValue("lehrkraft")
+		return c.HTML(http.StatusUnprocessableEntity, renderExamSettings(h.locale, edited, list, message))
+	}
+	exam.Sections = sections
+	exam.Lehrkraft = c.FormValue("lehrkraft")
+	if id, err := strconv.Atoi(c.FormValue("notenschluessel")); err == nil {
+		exam.NotenschluesselID = id
+	}
+	if err := h.store.UpdateExam(exam); err != nil {
+		return err
+	}
+
+	notenschluessel, err := h.examNotenschluessel(exam)
+	if err != nil {
+		return err
+	}
+	bewertungen, err := h.store.List(exam.ID)
+	if err != nil {
+		return err
+	}
+	for _, bewertung := range bewertungen {
+		bewertung.Sections = remapSections(bewertung.Sections, origins)
+		if err := h.store.Update(model.Bewerte(exam, notenschluessel, bewertung)); err != nil {
+			return err
+		}
+	}
+	return c.Redirect(http.StatusSeeOther, examURL(exam))
+}
+
+func examURL(exam model.Exam) string {
+	return "/exams/" + strconv.Itoa(exam.ID)
+}
+
+func formatDatum(datum time.Time) string {
+	if datum.IsZero() {
+		return ""
+	}
+	return datum.Format("02.01.2006")
+}
+
+func createExamNode(exam model.Exam) elem.Node {
+	return elem.Tr(nil,
+		elem.Td(nil, elem.A(attrs.Props{attrs.Href: examURL(exam)}, elem.Text(html.EscapeString(exam.Titel)))),
+		elem.Td(nil, elem.Text(html.EscapeString(exam.Fach))),
+		elem.Td(nil, elem.Text(html.EscapeString(exam.Klasse))),
+		elem.Td(nil, elem.Text(formatDatum(exam.Datum))),
+	)
+}
+
+// renderExams renders the list of exams and the form for a new one, filled
+// with form and the messages of fieldErrors after a failed submission.
+func (h *Controller) renderExams(exams []model.Exam, list []model.Notenschluessel, form model.Exam, fieldErrors FieldErrors) string {
+	datum := ""
+	if !form.Datum.IsZero() {
+		datum = form.Datum.Format(datumLayout)
+	}
+	datumClass := "input is-child"
+	if fieldErrors["datum"] != "" {
+		datumClass += " is-danger"
+	}
+	var sectionInputs []elem.Node
+	for _, section := range form.Sections {
+		sectionInputs = append(sectionInputs, createSectionInputNode(h.locale, -1, section))
+	}
+	if len(sectionInputs) == 0 {
+		sectionInputs = append(sectionInputs, createSectionInputNode(h.locale, -1, model.Section{}))
+	}
+
+	bodyContent := elem.Div(attrs.Props{attrs.Class: "container is-widescreen"},
+		elem.Div(attrs.Props{attrs.Class: "card tile is-vertical is-ancestor"},
+			elem.Header(attrs.Props{attrs.Class: "card-header"},
+				elem.P(attrs.Props{attrs.Class: "card-header-title"}, elem.Text("Klassenarbeiten"))),
+			elem.Div(attrs.Props{attrs.Class: "card-content"},
+				elem.Div(attrs.Props{attrs.Class: "content tile is-parent is-vertical gap"},
+					elem.Form(attrs.Props{attrs.Method: "post", attrs.Action: "/exams"},
+						elem.Div(attrs.Props{attrs.Class: "tile is-ancestor"},
+							elem.Div(attrs.Props{attrs.Class: "tile field is-parent is-vertical"},
+								createInputNode("input is-child", "titel", "Titel", form.Titel, fieldErrors["titel"])...,
+							),
+							elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
+								createInputNode("input is-child", "fach", "Fach", form.Fach, "")...,
+							),
+							elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
+								createInputNode("input is-child", "klasse", "Klasse", form.Klasse, "")...,
+							),
+							elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
+								createInputNode("input is-child", "lehrkraft", "Lehrkraft", form.Lehrkraft, "")...,
+							),
+							elem.Div(attrs.Props{attrs.Class: "tile field is-parent is-vertical"},
+								elem.Input(attrs.Props{
+									attrs.Type:  "date",
+									attrs.Name:  "datum",
+									attrs.Class: datumClass,
+									attrs.Value: datum,
+								},
+								),
+								elem.If[elem.Node](fieldErrors["datum"] != "",
+									elem.P(attrs.Props{attrs.Class: "help is-danger"}, elem.Text(html.EscapeString(fieldErrors["datum"]))),
+									elem.None(),
+								),
+							),
+							elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
+								createNotenschluesselSelectNode(list, form.NotenschluesselID),
+							),
+						),
+						elem.Div(attrs.Props{attrs.ID: "sections"}, sectionInputs...),
+						elem.If[elem.Node](fieldErrors["sections"] != "",
+							elem.P(attrs.Props{attrs.Class: "help is-danger"}, elem.Text(html.EscapeString(fieldErrors["sections"]))),
+							elem.None(),
+						),
+						elem.Div(attrs.Props{attrs.Class: "buttons"},
+							elem.Button(attrs.Props{
+								attrs.Type:    "button",
+								attrs.Class:   "button",
+								htmx.HXGet:    "/exams/section",
+								htmx.HXTarget: "#sections",
+								htmx.HXSwap:   "beforeend",
+							},
+								elem.Text("Teil hinzufügen"),
+							),
+							elem.Button(attrs.Props{
+								attrs.Type:  "submit",
+								attrs.Class: "button is-primary",
+							},
+								elem.Text("Anlegen"),
+							),
+						),
+					),
+					elem.Div(attrs.Props{attrs.Class: "table-container"},
+						elem.Table(attrs.Props{attrs.Class: "table is-hoverable"},
+							elem.THead(nil,
+								elem.Tr(nil,
+									elem.Th(nil, elem.Text("Titel")),
+									elem.Th(nil, elem.Text("Fach")),
+									elem.Th(nil, elem.Text("Klasse")),
+									elem.Th(nil, elem.Text("Datum")),
+								),
+							),
+							elem.TBody(nil,
+								elem.TransformEach(exams, createExamNode)...),
+						),
+					),
+				),
+			),
+		),
+	)
+
+	return renderPage(bodyContent)
+}
+
+func renderExamSettings(locale Locale, exam model.Exam, list []model.Notenschluessel, message string) string {
+	var sectionInputs []elem.Node
+	for i, section := range exam.Sections {
+		sectionInputs = append(sectionInputs, createSectionInputNode(locale, i, section))
+	}
+
+	bodyContent := elem.Div(attrs.Props{attrs.Class: "container is-widescreen"},
+		elem.Div(attrs.Props{attrs.Class: "card tile is-vertical is-ancestor"},
+			elem.Header(attrs.Props{attrs.Class: "card-header"},
+				elem.P(attrs.Props{attrs.Class: "card-header-title"}, elem.Text(html.EscapeString(exam.Titel)+" – Einstellungen"))),
+			elem.Div(attrs.Props{attrs.Class: "card-content"},
+				elem.Div(attrs.Props{attrs.Class: "content tile is-parent is-vertical gap"},
+					elem.If[elem.Node](message != "",
+						elem.Div(attrs.Props{attrs.Class: "notification is-danger is-light"}, elem.Text(html.EscapeString(message))),
+						elem.None(),
+					),
+					elem.P(nil, elem.Text("Änderungen an Max-Punkten, Gewichtungen oder Notenschlüssel berechnen alle Bewertungen neu. Ein Teil ohne Namen wird entfernt.")),
+					elem.Form(attrs.Props{attrs.Method: "post", attrs.Action: examURL(exam) + "/settings"},
+						elem.Div(attrs.Props{attrs.Class: "field"},
+							elem.Input(attrs.Props{
+								attrs.Type:        "text",
+								attrs.Name:        "lehrkraft",
+								attrs.Class:       "input",
+								attrs.Placeholder: "Lehrkraft",
+								attrs.Value:       html.EscapeString(exam.Lehrkraft),
+							}),
+						),
+						elem.Div(attrs.Props{attrs.ID: "sections"}, sectionInputs...),
+						elem.Div(attrs.Props{attrs.Class: "field"},
+							createNotenschluesselSelectNode(list, exam.NotenschluesselID),
+						),
+						elem.Div(attrs.Props{attrs.Class: "buttons"},
+							elem.Button(attrs.Props{
+								attrs.Type:    "button",
+								attrs.Class:   "button",
+								htmx.HXGet:    "/exams/section",
+								htmx.HXTarget: "#sections",
+								htmx.HXSwap:   "beforeend",
+							},
+								elem.Text("Teil hinzufügen"),
+							),
+							elem.Button(attrs.Props{
+								attrs.Type:  "submit",
+								attrs.Class: "button is-primary",
+							},
+								elem.Text("Speichern"),
+							),
+							elem.A(attrs.Props{
+								attrs.Class: "button",
+								attrs.Href:  examURL(exam),
+							},
+								elem.Text("Abbrechen"),
+							),
+						),
+					),
+				),
+			),
+		),
+	)
+
+	return renderPage(bodyContent)
+}
@@ -0,0 +1,333 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"echoTest/model"
+
+	"github.com/chasefleming/elem-go"
+	"github.com/chasefleming/elem-go/attrs"
+	"github.com/labstack/echo/v4"
+)
+
+// Exporter writes the Bewertungen of an exam in one file format.
+type Exporter interface {
+	ContentType() string
+	Extension() string
+	Export(w io.Writer, exam model.Exam, bewertungen []model.Bewertung) error
+}
+
+// ExportOptions are the settings of one export request, passed to the
+// function creating the Exporter.
+type ExportOptions struct {
+	// Ausgeschlossene includes the Bewertungen that are not gewertet.
+	Ausgeschlossene bool
+	// Notenschluessel is the scale of the exam.
+	Notenschluessel model.Notenschluessel
+	// Erstellt is the time printed as creation date.
+	Erstellt time.Time
+	// Locale formats the numbers.
+	Locale Locale
+}
+
+// exporters maps the format query parameter of the export route to the
+// function creating the Exporter for the options of the request.
+var exporters = map[string]func(options ExportOptions) Exporter{
+	"csv":  func(options ExportOptions) Exporter { return csvExporter{options} },
+	"xlsx": func(options ExportOptions) Exporter { return xlsxExporter{options} },
+	"pdf":  func(options ExportOptions) Exporter { return pdfExporter{options} },
+}
+
+// exportBewertungenRoute streams the Bewertungen of an exam as a download in
+// the format given by ?format=, a PDF by default.
+func (h *Controller) exportBewertungenRoute(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "pdf"
+	}
+	newExporter, ok := exporters[format]
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unbekanntes Format: "+format)
+	}
+	exam, err := h.loadExam(c)
+	if err != nil {
+		return err
+	}
+	bewertungen, err := h.store.List(exam.ID)
+	if err != nil {
+		return err
+	}
+	notenschluessel, err := h.examNotenschluessel(exam)
+	if err != nil {
+		return err
+	}
+	exporter := newExporter(ExportOptions{
+		Ausgeschlossene: c.QueryParam("ausgeschlossen") == "1",
+		Notenschluessel: notenschluessel,
+		Erstellt:        h.now(),
+		Locale:          h.locale,
+	})
+
+	return h.sendExport(c, exportName(exam, h.now()), exporter.Extension(), exporter.ContentType(), func(w io.Writer) error {
+		return exporter.Export(w, exam, bewertungen)
+	})
+}
+
+// sendExport sends the file written by export as a download named
+// name.extension and keeps a copy in the export archive if it is enabled.
+// The file is rendered completely first, so a failing export ends in an
+// error page instead of a truncated download or a partial archive file.
+func (h *Controller) sendExport(c echo.Context, name, extension, contentType string, export func(w io.Writer) error) error {
+	var content bytes.Buffer
+	if err := export(&content); err != nil {
+		return err
+	}
+	if h.exportArchive != "" {
+		if err := archiveExport(h.exportArchive, name+"-"+h.now().Format("20060102-150405"), extension, content.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, contentDisposition(name+"."+extension))
+	return c.Blob(http.StatusOK, contentType, content.Bytes())
+}
+
+// archiveExport stores the content in a new file in dir and removes the
+// file again if it cannot be written completely.
+func archiveExport(dir, name, extension string, content []byte) error {
+	file, err := createArchiveFile(dir, name, extension)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+// exportName returns the file name of an export without extension, made
+// from the exam title and its date, or the given time if it has none.
+func exportName(exam model.Exam, now time.Time) string {
+	datum := exam.Datum
+	if datum.IsZero() {
+		datum = now
+	}
+	title := fileNamePart(exam.Titel)
+	if title == "" {
+		title = "Bewertungen"
+	}
+	return title + "-" + datum.Format(datumLayout)
+}
+
+// fileNamePart replaces everything but letters, digits, - and _ in text so
+// it can be used in a file name.
+func fileNamePart(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(text))
+}
+
+// contentDisposition marks the response as a download. Browsers that do not
+// understand the UTF-8 filename* get a fallback without umlauts.
+func contentDisposition(filename string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, filename)
+	return fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, fallback, url.PathEscape(filename))
+}
+
+// createArchiveFile creates a new file in dir. If a file with the name
+// exists already, a counter is appended instead of overwriting it.
+func createArchiveFile(dir, name, extension string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	for i := 1; ; i++ {
+		filename := name + "." + extension
+		if i > 1 {
+			filename = fmt.Sprintf("%s-%d.%s", name, i, extension)
+		}
+		file, err := os.OpenFile(filepath.Join(dir, filename), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if !errors.Is(err, fs.ErrExist) {
+			return file, err
+		}
+	}
+}
+
+// exportTable returns the header and one row per gewertete Bewertung with
+// all computed fields. With ausgeschlossene the other Bewertungen are kept
+// and marked in the Gewertet column. Cells are either a string or a
+// float64.
+func exportTable(exam model.Exam, bewertungen []model.Bewertung, ausgeschlossene bool) [][]any {
+	header := []any{"Vorname", "Nachname"}
+	for _, section := range exam.Sections {
+		header = append(header, section.Name+"-Punkte", section.Name+"-Prozent", section.Name+"-Note")
+	}
+	header = append(header, "Gesamt-Prozent", "Gesamt-Note", "Gewertet", "Grund")
+
+	rows := [][]any{header}
+	for _, bewertung := range bewertungen {
+		if !bewertung.Gewertet && !ausgeschlossene {
+			continue
+		}
+		row := []any{bewertung.Vorname, bewertung.Nachname}
+		for _, result := range bewertung.Sections {
+			row = append(row, result.Punkte, result.Prozent, result.Note.Name)
+		}
+		gewertet := "nein"
+		if bewertung.Gewertet {
+			gewertet = "ja"
+		}
+		row = append(row, bewertung.GesamtProzent, bewertung.GesamtNote.Name, gewertet, bewertung.Grund)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+type csvExporter struct {
+	options ExportOptions
+}
+
+func (csvExporter) ContentType() string { return "text/csv; charset=utf-8" }
+
+func (csvExporter) Extension() string { return "csv" }
+
+// Export writes the table with a byte order mark, so Excel detects UTF-8,
+// and separates the columns with a semicolon if the locale uses a decimal
+// comma.
+func (e csvExporter) Export(w io.Writer, exam model.Exam, bewertungen []model.Bewertung) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = true
+	if e.options.Locale.Decimal == "," {
+		writer.Comma = ';'
+	}
+	for _, row := range exportTable(exam, bewertungen, e.options.Ausgeschlossene) {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			switch value := cell.(type) {
+			case float64:
+				record[i] = e.options.Locale.FormatNumber(value, 2)
+			case string:
+				record[i] = value
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+type xlsxExporter struct {
+	options ExportOptions
+}
+
+func (xlsxExporter) ContentType() string {
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+func (xlsxExporter) Extension() string { return "xlsx" }
+
+// xlsxFiles are the parts of a workbook with a single sheet besides the
+// sheet itself.
+var xlsxFiles = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Bewertungen" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+// Export writes a minimal workbook. Numbers keep their full precision and
+// are formatted by the spreadsheet application.
+func (e xlsxExporter) Export(w io.Writer, exam model.Exam, bewertungen []model.Bewertung) error {
+	archive := zip.NewWriter(w)
+	for _, file := range xlsxFiles {
+		part, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(part, file.content); err != nil {
+			return err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n")
+	io.WriteString(sheet, `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range exportTable(exam, bewertungen, e.options.Ausgeschlossene) {
+		fmt.Fprintf(sheet, `<row r="%d">`, i+1)
+		for j, cell := range row {
+			ref := xlsxColumn(j) + strconv.Itoa(i+1)
+			switch value := cell.(type) {
+			case float64:
+				fmt.Fprintf(sheet, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(value, 'f', -1, 64))
+			case string:
+				fmt.Fprintf(sheet, `<c r="%s" t="inlineStr"><is><t>`, ref)
+				xml.EscapeText(sheet, []byte(value))
+				io.WriteString(sheet, `</t></is></c>`)
+			}
+		}
+		io.WriteString(sheet, `</row>`)
+	}
+	if _, err := io.WriteString(sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// xlsxColumn returns the letters naming the column at index i, A for 0.
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// createExportLinkNode renders a download link for the export with the
+// given query.
+func createExportLinkNode(exam model.Exam, query, label string) elem.Node {
+	return elem.A(attrs.Props{
+		attrs.Class:    "button",
+		attrs.Href:     examURL(exam) + "/export?" + query,
+		attrs.Download: "",
+	},
+		elem.Text("Export "+label),
+	)
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"echoTest/model"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func exportRequest(t *testing.T, h *Controller, exam model.Exam, query string) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, examURL(exam)+"/export?"+query, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(exam.ID))
+	assert.NoError(t, h.exportBewertungenRoute(c))
+	return rec
+}
+
+func TestExportBewertungenRoute(t *testing.T) {
+	exam := createTestExam(t)
+	exam.Datum = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, testController.store.UpdateExam(exam))
+	bewertung := model.Bewerte(exam, model.StandardNotenschluessel, model.Bewertung{
+		ExamID:   exam.ID,
+		Vorname:  "Jürgen",
+		Nachname: "Müller",
+		Sections: []model.SectionResult{{Punkte: 10}, {Punkte: 22.5}},
+		Grund:    "krank",
+	})
+	_, err := testController.store.Save(bewertung)
+	assert.NoError(t, err)
+	bewertung.Vorname, bewertung.Nachname, bewertung.Gewertet, bewertung.Grund = "Anna", "Schmidt", true, ""
+	_, err = testController.store.Save(bewertung)
+	assert.NoError(t, err)
+
+	rec := exportRequest(t, testController, exam, "format=csv")
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "attachment")
+	assert.Contains(t, rec.Body.String(), "Vorname;Nachname;HV-Punkte;HV-Prozent;HV-Note;LV-Punkte;LV-Prozent;LV-Note;Gesamt-Prozent;Gesamt-Note;Gewertet;Grund\r\n")
+	assert.Contains(t, rec.Body.String(), "Anna;Schmidt;10,00;50,00;4;22,50;75,00;3;62,50;4;ja;\r\n")
+	assert.NotContains(t, rec.Body.String(), "Müller")
+	rec = exportRequest(t, testController, exam, "format=csv&ausgeschlossen=1")
+	assert.Contains(t, rec.Body.String(), "Jürgen;Müller;10,00;50,00;4;22,50;75,00;3;62,50;4;nein;krank\r\n")
+	rec = exportRequest(t, New(Options{Store: testController.store, Locale: localeEN}), exam, "format=csv")
+	assert.Contains(t, rec.Body.String(), "Anna,Schmidt,10.00,50.00,4,22.50,75.00,3,62.50,4,ja,\r\n")
+
+	rec = exportRequest(t, testController, exam, "format=xlsx&ausgeschlossen=1")
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if assert.NoError(t, err) {
+		sheet, err := archive.Open("xl/worksheets/sheet1.xml")
+		if assert.NoError(t, err) {
+			content, _ := io.ReadAll(sheet)
+			assert.Contains(t, string(content), `<c r="B2" t="inlineStr"><is><t>Müller</t></is></c><c r="C2"><v>10</v></c>`)
+		}
+	}
+
+	rec = exportRequest(t, testController, exam, "format=pdf")
+	assert.Equal(t, `attachment; filename="Englischarbeit-2024-03-01.pdf"; filename*=UTF-8''Englischarbeit-2024-03-01.pdf`, rec.Header().Get(echo.HeaderContentDisposition))
+	assert.Equal(t, "application/pdf", rec.Header().Get(echo.HeaderContentType))
+	assert.True(t, bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF")))
+}
+
+func TestXlsxColumn(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumn(0))
+	assert.Equal(t, "Z", xlsxColumn(25))
+	assert.Equal(t, "AA", xlsxColumn(26))
+	assert.Equal(t, "BA", xlsxColumn(52))
+}
+
+func TestExportName(t *testing.T) {
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	assert.Equal(t, "Englisch_Klasse_7b-2024-05-06", exportName(model.Exam{Titel: "Englisch Klasse 7b"}, now))
+	assert.Equal(t, "Prüfung_1_2-2024-03-01", exportName(model.Exam{Titel: "Prüfung 1/2", Datum: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}, now))
+	assert.Equal(t, `attachment; filename="Pr_fung.pdf"; filename*=UTF-8''Pr%C3%BCfung.pdf`, contentDisposition("Prüfung.pdf"))
+}
+
+func TestExportArchive(t *testing.T) {
+	exam := createTestExam(t)
+	archive := t.TempDir()
+	h := New(Options{
+		Store:         testController.store,
+		Now:           func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) },
+		ExportArchive: archive,
+	})
+
+	first := exportRequest(t, h, exam, "format=csv")
+	exportRequest(t, h, exam, "format=csv")
+
+	files, err := os.ReadDir(archive)
+	assert.NoError(t, err)
+	if assert.Len(t, files, 2) {
+		assert.Equal(t, "Englischarbeit-2024-05-06-20240506-070809-2.csv", files[0].Name())
+		assert.Equal(t, "Englischarbeit-2024-05-06-20240506-070809.csv", files[1].Name())
+		content, err := os.ReadFile(filepath.Join(archive, files[1].Name()))
+		assert.NoError(t, err)
+		assert.Equal(t, first.Body.String(), string(content))
+	}
+}
+
+func TestSendExportError(t *testing.T) {
+	archive := t.TempDir()
+	h := New(Options{Store: testController.store, ExportArchive: archive})
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	err := h.sendExport(c, "Bewertungen", "pdf", "application/pdf", func(w io.Writer) error {
+		io.WriteString(w, "%PDF-1.3")
+		return errors.New("kaputt")
+	})
+
+	assert.EqualError(t, err, "kaputt")
+	assert.False(t, c.Response().Committed)
+	assert.Empty(t, rec.Body.String())
+	files, err := os.ReadDir(archive)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
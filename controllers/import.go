@@ -0,0 +1,281 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"echoTest/model"
+
+	"github.com/chasefleming/elem-go"
+	"github.com/chasefleming/elem-go/attrs"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// importResult reports how many lines of a CSV file were imported and why
+// the others were skipped.
+type importResult struct {
+	Imported int
+	Skipped  []importSkip
+}
+
+// importOhnePunkte is the Grund of imported Bewertungen that lack points.
+const importOhnePunkte = "noch keine Punkte"
+
+type importSkip struct {
+	Line   int
+	Reason string
+}
+
+// importBewertungenRoute creates a Bewertung for every line of the uploaded
+// CSV file and shows the exam page with a report of the skipped lines.
+func (h *Controller) importBewertungenRoute(c echo.Context) error {
+	exam, err := h.loadExam(c)
+	if err != nil {
+		return err
+	}
+	file, err := c.FormFile("datei")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bitte eine CSV-Datei auswählen")
+	}
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	content, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.importBewertungen(exam, content)
+	if err != nil {
+		return err
+	}
+
+	bewertungen, err := h.store.List(exam.ID)
+	if err != nil {
+		return err
+	}
+	notenschluessel, err := h.examNotenschluessel(exam)
+	if err != nil {
+		return err
+	}
+	form := elem.Div(nil,
+		createImportReportNode(result),
+		createBewertungFormNode(exam, url.Values{}, nil),
+	)
+	return c.HTML(http.StatusOK, renderBewertungen(h.locale, exam, notenschluessel, bewertungen, Ansicht{}, form))
+}
+
+// importBewertungen reads the CSV content and stores a Bewertung for every
+// valid line. Lines are checked like the form, so names that are already
+// taken, also by an earlier line of the file, are skipped. Lines with
+// empty points are imported as not gewertet, so a plain list of names can
+// be imported before grading without counting as a 6.
+func (h *Controller) importBewertungen(exam model.Exam, content []byte) (importResult, error) {
+	var result importResult
+	bewertungen, err := h.store.List(exam.ID)
+	if err != nil {
+		return result, err
+	}
+	notenschluessel, err := h.examNotenschluessel(exam)
+	if err != nil {
+		return result, err
+	}
+
+	content, err = decodeCSV(content)
+	if err != nil {
+		return result, err
+	}
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = detectDelimiter(content)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	var columns map[string]int
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			result.Skipped = append(result.Skipped, importSkip{Line: parseErr.Line, Reason: "Zeile kann nicht gelesen werden"})
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if columns == nil {
+			var header bool
+			columns, header = mapColumns(exam, record)
+			if header {
+				continue
+			}
+		}
+
+		values := url.Values{}
+		for field, column := range columns {
+			if column < len(record) {
+				values.Set(field, strings.TrimSpace(record[column]))
+			}
+		}
+		if isEmptyRecord(values) {
+			continue
+		}
+		ohnePunkte := false
+		for i := range exam.Sections {
+			if values.Get(punkteField(i)) == "" {
+				values.Set(punkteField(i), "0")
+				ohnePunkte = true
+			}
+		}
+
+		bewertung, fieldErrors := checkBewertung(h.locale, exam, notenschluessel, bewertungen, values, 0)
+		if len(fieldErrors) > 0 {
+			result.Skipped = append(result.Skipped, importSkip{Line: line, Reason: describeFieldErrors(exam, fieldErrors)})
+			continue
+		}
+		if ohnePunkte {
+			bewertung.Gewertet = false
+			bewertung.Grund = importOhnePunkte
+		}
+		saved, err := h.store.Save(bewertung)
+		if errors.Is(err, model.ErrExists) {
+			result.Skipped = append(result.Skipped, importSkip{Line: line, Reason: "Diese Person ist bereits eingetragen"})
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		bewertungen = append(bewertungen, saved)
+		result.Imported++
+	}
+	return result, nil
+}
+
+// decodeCSV converts the content to UTF-8. Files that are not valid UTF-8
+// are read as Windows-1252, the superset of ISO-8859-1 that Excel writes.
+func decodeCSV(content []byte) ([]byte, error) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(content) {
+		return content, nil
+	}
+	return charmap.Windows1252.NewDecoder().Bytes(content)
+}
+
+// detectDelimiter returns the separator that occurs most often in the first
+// line. Excel uses a semicolon when the decimal separator is a comma.
+func detectDelimiter(content []byte) rune {
+	first, _, _ := bytes.Cut(content, []byte("\n"))
+	delimiter, most := ',', 0
+	for _, candidate := range []rune{';', ',', '\t'} {
+		if count := bytes.Count(first, []byte(string(candidate))); count > most {
+			delimiter, most = candidate, count
+		}
+	}
+	return delimiter
+}
+
+// mapColumns maps the form fields to the columns of the first record if it
+// is a header, recognised by a Nachname column. Otherwise the columns are
+// expected in the order Vorname, Nachname and the points of every section.
+func mapColumns(exam model.Exam, record []string) (map[string]int, bool) {
+	columns := map[string]int{}
+	for column, name := range record {
+		name = strings.ToLower(strings.TrimSpace(name))
+		name = strings.TrimSuffix(strings.TrimSuffix(name, "punkte"), "-")
+		name = strings.TrimSpace(name)
+		switch name {
+		case "vorname", "first name", "firstname":
+			columns["vorname"] = column
+		case "nachname", "name", "familienname", "last name", "lastname":
+			columns["nachname"] = column
+		}
+		for i, section := range exam.Sections {
+			if name == strings.ToLower(section.Name) {
+				columns[punkteField(i)] = column
+			}
+		}
+	}
+	if _, ok := columns["nachname"]; ok {
+		return columns, true
+	}
+
+	columns = map[string]int{"vorname": 0, "nachname": 1}
+	for i := range exam.Sections {
+		columns[punkteField(i)] = i + 2
+	}
+	return columns, false
+}
+
+func isEmptyRecord(values url.Values) bool {
+	for _, value := range values {
+		if value[0] != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// describeFieldErrors joins the messages in the order of the form fields.
+func describeFieldErrors(exam model.Exam, fieldErrors FieldErrors) string {
+	var messages []string
+	if message, ok := fieldErrors["nachname"]; ok {
+		messages = append(messages, message)
+	}
+	for i, section := range exam.Sections {
+		if message, ok := fieldErrors[punkteField(i)]; ok {
+			messages = append(messages, section.Name+": "+message)
+		}
+	}
+	return strings.Join(messages, ", ")
+}
+
+func createImportFormNode(exam model.Exam) elem.Node {
+	return elem.Form(attrs.Props{
+		attrs.Method: "post",
+		attrs.Action: examURL(exam) + "/import",
+		"enctype":    "multipart/form-data",
+		attrs.Class:  "field has-addons",
+	},
+		elem.Div(attrs.Props{attrs.Class: "control"},
+			elem.Input(attrs.Props{
+				attrs.Type:   "file",
+				attrs.Name:   "datei",
+				attrs.Accept: ".csv,text/csv",
+				attrs.Class:  "input",
+			}),
+		),
+		elem.Div(attrs.Props{attrs.Class: "control"},
+			elem.Button(attrs.Props{attrs.Type: "submit", attrs.Class: "button"},
+				elem.Text("CSV importieren"),
+			),
+		),
+	)
+}
+
+func createImportReportNode(result importResult) elem.Node {
+	class := "notification is-success is-light"
+	if len(result.Skipped) > 0 {
+		class = "notification is-warning is-light"
+	}
+	return elem.Div(attrs.Props{attrs.Class: class},
+		elem.P(nil, elem.Text(strconv.Itoa(result.Imported)+" Bewertungen importiert, "+strconv.Itoa(len(result.Skipped))+" Zeilen übersprungen")),
+		elem.Ul(nil, elem.TransformEach(result.Skipped, func(skip importSkip) elem.Node {
+			return elem.Li(nil, elem.Text("Zeile "+strconv.Itoa(skip.Line)+": "+html.EscapeString(skip.Reason)))
+		})...),
+	)
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"echoTest/model"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportBewertungenRoute(t *testing.T) {
+	exam := createTestExam(t)
+	_, err := testController.store.Save(model.Bewertung{ExamID: exam.ID, Vorname: "Max", Nachname: "Muster"})
+	assert.NoError(t, err)
+
+	// ISO-8859-1 as written by Excel, with ü as 0xfc
+	content := "Nachname;Vorname;HV-Punkte;LV-Punkte\r\n" +
+		"M\xfcller;J\xfcrgen;10,5;20\r\n" +
+		"Muster;Max;1;1\r\n" +
+		"Schmidt;Anna;;\r\n" +
+		"Meyer;Tom;25;1\r\n" +
+		";;;\r\n" +
+		"Schmidt;Anna;2;2\r\n"
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("datei", "klasse.csv")
+	assert.NoError(t, err)
+	part.Write([]byte(content))
+	writer.Close()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, examURL(exam)+"/import", &body)
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(exam.ID))
+
+	err = testController.importBewertungenRoute(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "2 Bewertungen importiert, 3 Zeilen übersprungen")
+	assert.Contains(t, rec.Body.String(), "Zeile 3: Diese Person ist bereits eingetragen")
+	assert.Contains(t, rec.Body.String(), "Zeile 5: HV: Höchstens 20,00 Punkte")
+	assert.Contains(t, rec.Body.String(), "Zeile 7: Diese Person ist bereits eingetragen")
+
+	bewertungen, err := testController.store.List(exam.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, bewertungen, 3) {
+		assert.Equal(t, "Jürgen", bewertungen[1].Vorname)
+		assert.Equal(t, "Müller", bewertungen[1].Nachname)
+		assert.Equal(t, 10.5, bewertungen[1].Sections[0].Punkte)
+		assert.True(t, bewertungen[1].Gewertet)
+		assert.Equal(t, "Anna", bewertungen[2].Vorname)
+		assert.Equal(t, 0.0, bewertungen[2].Sections[1].Punkte)
+		assert.False(t, bewertungen[2].Gewertet)
+		assert.Equal(t, "noch keine Punkte", bewertungen[2].Grund)
+	}
+}
+
+func TestImportWithoutHeader(t *testing.T) {
+	exam := createTestExam(t)
+
+	result, err := testController.importBewertungen(exam, []byte("Anna,Schmidt,10,15\nTom,Meyer,5,\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Empty(t, result.Skipped)
+
+	bewertungen, err := testController.store.List(exam.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, bewertungen, 2) {
+		assert.Equal(t, "Schmidt", bewertungen[0].Nachname)
+		assert.Equal(t, 15.0, bewertungen[0].Sections[1].Punkte)
+		assert.True(t, bewertungen[0].Gewertet)
+		assert.False(t, bewertungen[1].Gewertet)
+	}
+}
+
+func TestImportEscaping(t *testing.T) {
+	exam := createTestExam(t)
+	exam.Sections[0].Name = "<i>HV</i>"
+	assert.NoError(t, testController.store.UpdateExam(exam))
+
+	result, err := testController.importBewertungen(exam, []byte("<script>alert(1)</script>;x\"><b>;10;15\nMeyer;Tom;50;1\n"))
+	assert.NoError(t, err)
+	report := createImportReportNode(result).Render()
+	assert.Contains(t, report, "&lt;i&gt;HV&lt;/i&gt;: Höchstens")
+	assert.NotContains(t, report, "<i>")
+
+	bewertungen, err := testController.store.List(exam.ID)
+	assert.NoError(t, err)
+	page := renderBewertungen(localeDE, exam, model.StandardNotenschluessel, bewertungen, Ansicht{}, createImportReportNode(result))
+	assert.Contains(t, page, "&lt;script&gt;alert(1)&lt;/script&gt;")
+	assert.NotContains(t, page, "<script>alert")
+	assert.NotContains(t, page, `"><b>`)
+}
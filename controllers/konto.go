@@ -0,0 +1,327 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"echoTest/model"
+
+	"github.com/chasefleming/elem-go"
+	"github.com/chasefleming/elem-go/attrs"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// sitzungCookie holds the token of the Sitzung.
+	sitzungCookie = "echotest_sitzung"
+	// sitzungDauer is how long a login lasts.
+	sitzungDauer = 12 * time.Hour
+	// kontoKey is the context key of the name of the logged-in Konto.
+	kontoKey = "konto"
+)
+
+// sitzungen are the logins of the running process, kept in memory so a
+// restart logs everybody out.
+type sitzungen struct {
+	mu      sync.Mutex
+	byToken map[string]sitzung
+}
+
+type sitzung struct {
+	Konto  string
+	Ablauf time.Time
+}
+
+func newSitzungen() *sitzungen {
+	return &sitzungen{byToken: map[string]sitzung{}}
+}
+
+// start returns the token of a new Sitzung of the Konto and drops the
+// expired ones.
+func (s *sitzungen) start(konto string, now time.Time) (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for t, sitzung := range s.byToken {
+		if !now.Before(sitzung.Ablauf) {
+			delete(s.byToken, t)
+		}
+	}
+	s.byToken[token] = sitzung{Konto: konto, Ablauf: now.Add(sitzungDauer)}
+	return token, nil
+}
+
+// konto returns the name of the Konto logged in with the token.
+func (s *sitzungen) konto(token string, now time.Time) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sitzung, ok := s.byToken[token]
+	if !ok || !now.Before(sitzung.Ablauf) {
+		delete(s.byToken, token)
+		return "", false
+	}
+	return sitzung.Konto, true
+}
+
+func (s *sitzungen) beenden(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.byToken, token)
+}
+
+// requireLogin only lets requests of a logged-in Konto through. Scripts
+// using the JSON API may send HTTP basic auth instead of the cookie.
+func (h *Controller) requireLogin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if cookie, err := c.Cookie(sitzungCookie); err == nil {
+			if konto, ok := h.sitzungen.konto(cookie.Value, h.now()); ok {
+				c.Set(kontoKey, konto)
+				return next(c)
+			}
+		}
+		if name, passwort, ok := c.Request().BasicAuth(); ok {
+			if konto, ok := model.PruefeAnmeldung(h.store, name, passwort); ok {
+				c.Set(kontoKey, konto.Name)
+				return next(c)
+			}
+		}
+
+		if strings.HasPrefix(c.Request().URL.Path, "/api/") {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="echoTest"`)
+			return echo.NewHTTPError(http.StatusUnauthorized, "Bitte anmelden")
+		}
+		target := "/login"
+		if c.Request().Method == http.MethodGet {
+			target += "?next=" + url.QueryEscape(c.Request().URL.RequestURI())
+		}
+		if isHTMX(c) {
+			c.Response().Header().Set("HX-Redirect", target)
+			return c.NoContent(http.StatusUnauthorized)
+		}
+		return c.Redirect(http.StatusSeeOther, target)
+	}
+}
+
+// anmelden starts a Sitzung and sends the browser on to next.
+func (h *Controller) anmelden(c echo.Context, konto string, next string) error {
+	token, err := h.sitzungen.start(konto, h.now())
+	if err != nil {
+		return err
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     sitzungCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(sitzungDauer / time.Second),
+		HttpOnly: true,
+		Secure:   c.IsTLS(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return c.Redirect(http.StatusSeeOther, localTarget(next))
+}
+
+// localTarget returns next if it is a path on this server, so the login
+// cannot be used to redirect elsewhere.
+func localTarget(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/exams"
+	}
+	return next
+}
+
+// hasKonten reports whether any Konto exists; until then /setup creates
+// the first one.
+func (h *Controller) hasKonten() (bool, error) {
+	konten, err := h.store.ListKonten()
+	return len(konten) > 0, err
+}
+
+func (h *Controller) renderLoginRoute(c echo.Context) error {
+	vorhanden, err := h.hasKonten()
+	if err != nil {
+		return err
+	}
+	if !vorhanden {
+		return c.Redirect(http.StatusSeeOther, "/setup")
+	}
+	return c.HTML(http.StatusOK, renderLogin(c.QueryParam("next"), "", ""))
+}
+
+func (h *Controller) loginRoute(c echo.Context) error {
+	name := c.FormValue("name")
+	next := c.FormValue("next")
+	konto, ok := model.PruefeAnmeldung(h.store, name, c.FormValue("passwort"))
+	if !ok {
+		return c.HTML(http.StatusUnauthorized, renderLogin(next, name, "Name oder Passwort ist falsch"))
+	}
+	return h.anmelden(c, konto.Name, next)
+}
+
+func (h *Controller) logoutRoute(c echo.Context) error {
+	if cookie, err := c.Cookie(sitzungCookie); err == nil {
+		h.sitzungen.beenden(cookie.Value)
+	}
+	c.SetCookie(&http.Cookie{Name: sitzungCookie, Path: "/", MaxAge: -1, HttpOnly: true})
+	if isHTMX(c) {
+		c.Response().Header().Set("HX-Redirect", "/login")
+		return c.NoContent(http.StatusOK)
+	}
+	return c.Redirect(http.StatusSeeOther, "/login")
+}
+
+// renderSetupRoute shows the form for the first Konto, which needs no
+// login. Once a Konto exists, further ones are added on /konten.
+func (h *Controller) renderSetupRoute(c echo.Context) error {
+	vorhanden, err := h.hasKonten()
+	if err != nil {
+		return err
+	}
+	if vorhanden {
+		return c.Redirect(http.StatusSeeOther, "/login")
+	}
+	return c.HTML(http.StatusOK, renderSetup("", ""))
+}
+
+func (h *Controller) setupRoute(c echo.Context) error {
+	vorhanden, err := h.hasKonten()
+	if err != nil {
+		return err
+	}
+	if vorhanden {
+		return c.Redirect(http.StatusSeeOther, "/login")
+	}
+	konto, message, err := h.createKonto(c)
+	if err != nil {
+		return err
+	}
+	if message != "" {
+		return c.HTML(http.StatusUnprocessableEntity, renderSetup(c.FormValue("name"), message))
+	}
+	return h.anmelden(c, konto.Name, "/exams")
+}
+
+func (h *Controller) renderKontenRoute(c echo.Context) error {
+	konten, err := h.store.ListKonten()
+	if err != nil {
+		return err
+	}
+	return c.HTML(http.StatusOK, renderKonten(konten, "", ""))
+}
+
+func (h *Controller) addKontoRoute(c echo.Context) error {
+	_, message, err := h.createKonto(c)
+	if err != nil {
+		return err
+	}
+	if message != "" {
+		konten, err := h.store.ListKonten()
+		if err != nil {
+			return err
+		}
+		return c.HTML(http.StatusUnprocessableEntity, renderKonten(konten, c.FormValue("name"), message))
+	}
+	return c.Redirect(http.StatusSeeOther, "/konten")
+}
+
+// createKonto stores a Konto from the form. The message tells the user why
+// the input cannot be used.
+func (h *Controller) createKonto(c echo.Context) (model.Konto, string, error) {
+	if c.FormValue("passwort") != c.FormValue("passwort2") {
+		return model.Konto{}, "Die Passwörter stimmen nicht überein", nil
+	}
+	konto, err := model.NewKonto(c.FormValue("name"), c.FormValue("passwort"))
+	if err != nil {
+		return model.Konto{}, err.Error(), nil
+	}
+	konto, err = h.store.SaveKonto(konto)
+	if errors.Is(err, model.ErrExists) {
+		return model.Konto{}, "Ein Konto mit diesem Namen gibt es bereits", nil
+	}
+	return konto, "", err
+}
+
+// createKontoFieldsNode renders the inputs for name and password of a new
+// Konto.
+func createKontoFieldsNode(name, message string) elem.Node {
+	return elem.Div(nil,
+		elem.Div(attrs.Props{attrs.Class: "field"},
+			createInputNode("input", "name", "Name", name, "")...,
+		),
+		elem.Div(attrs.Props{attrs.Class: "field"},
+			elem.Input(attrs.Props{attrs.Type: "password", attrs.Name: "passwort", attrs.Class: "input", attrs.Placeholder: "Passwort (mindestens 8 Zeichen)", attrs.Autocomplete: "new-password"}),
+		),
+		elem.Div(attrs.Props{attrs.Class: "field"},
+			elem.Input(attrs.Props{attrs.Type: "password", attrs.Name: "passwort2", attrs.Class: "input", attrs.Placeholder: "Passwort wiederholen", attrs.Autocomplete: "new-password"}),
+		),
+		elem.If[elem.Node](message != "",
+			elem.Div(attrs.Props{attrs.Class: "notification is-danger is-light"}, elem.Text(message)),
+			elem.None(),
+		),
+	)
+}
+
+// renderKontoPage renders a small card with the title and content.
+func renderKontoPage(title string, content ...elem.Node) string {
+	return renderPage(elem.Div(attrs.Props{attrs.Class: "container is-max-desktop"},
+		elem.Div(attrs.Props{attrs.Class: "card"},
+			elem.Header(attrs.Props{attrs.Class: "card-header"},
+				elem.P(attrs.Props{attrs.Class: "card-header-title"}, elem.Text(title))),
+			elem.Div(attrs.Props{attrs.Class: "card-content"}, content...),
+		),
+	))
+}
+
+func renderLogin(next, name, message string) string {
+	return renderKontoPage("Anmelden",
+		elem.Form(attrs.Props{attrs.Method: "post", attrs.Action: "/login"},
+			elem.Input(attrs.Props{attrs.Type: "hidden", attrs.Name: "next", attrs.Value: html.EscapeString(localTarget(next))}),
+			elem.Div(attrs.Props{attrs.Class: "field"},
+				createInputNode("input", "name", "Name", name, "")...,
+			),
+			elem.Div(attrs.Props{attrs.Class: "field"},
+				elem.Input(attrs.Props{attrs.Type: "password", attrs.Name: "passwort", attrs.Class: "input", attrs.Placeholder: "Passwort", attrs.Autocomplete: "current-password"}),
+			),
+			elem.If[elem.Node](message != "",
+				elem.Div(attrs.Props{attrs.Class: "notification is-danger is-light"}, elem.Text(message)),
+				elem.None(),
+			),
+			elem.Button(attrs.Props{attrs.Type: "submit", attrs.Class: "button is-primary"}, elem.Text("Anmelden")),
+		),
+	)
+}
+
+func renderSetup(name, message string) string {
+	return renderKontoPage("Erstes Konto anlegen",
+		elem.P(attrs.Props{attrs.Class: "block"}, elem.Text("Noch gibt es kein Konto. Das erste Konto kann ohne Anmeldung angelegt werden, weitere danach unter Konten.")),
+		elem.Form(attrs.Props{attrs.Method: "post", attrs.Action: "/setup"},
+			createKontoFieldsNode(name, message),
+			elem.Button(attrs.Props{attrs.Type: "submit", attrs.Class: "button is-primary"}, elem.Text("Anlegen")),
+		),
+	)
+}
+
+func renderKonten(konten []model.Konto, name, message string) string {
+	return renderKontoPage("Konten",
+		elem.Table(attrs.Props{attrs.Class: "table is-fullwidth"},
+			elem.TBody(nil, elem.TransformEach(konten, func(konto model.Konto) elem.Node {
+				return elem.Tr(nil, elem.Td(nil, elem.Text(html.EscapeString(konto.Name))))
+			})...),
+		),
+		elem.Form(attrs.Props{attrs.Method: "post", attrs.Action: "/konten"},
+			createKontoFieldsNode(name, message),
+			elem.Button(attrs.Props{attrs.Type: "submit", attrs.Class: "button is-primary"}, elem.Text("Konto anlegen")),
+		),
+	)
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"echoTest/model"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogin(t *testing.T) {
+	jsonStore, err := model.NewJSONStore(filepath.Join(t.TempDir(), "bewertungen.json"))
+	assert.NoError(t, err)
+	e := echo.New()
+	New(Options{Store: jsonStore}).Register(e)
+
+	request := func(method, target string, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request(http.MethodGet, "/exams", nil)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/login?next=%2Fexams", rec.Header().Get(echo.HeaderLocation))
+	rec = request(http.MethodGet, "/login", nil)
+	assert.Equal(t, "/setup", rec.Header().Get(echo.HeaderLocation))
+
+	rec = request(http.MethodPost, "/setup", url.Values{"name": {"Frau Muster"}, "passwort": {"kurz"}, "passwort2": {"kurz"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "mindestens 8 Zeichen")
+	rec = request(http.MethodPost, "/setup", url.Values{"name": {"Frau Muster"}, "passwort": {"geheim123"}, "passwort2": {"geheim123"}})
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Len(t, rec.Result().Cookies(), 1)
+
+	rec = request(http.MethodGet, "/setup", nil)
+	assert.Equal(t, "/login", rec.Header().Get(echo.HeaderLocation))
+	konten, err := jsonStore.ListKonten()
+	assert.NoError(t, err)
+	assert.Len(t, konten, 1)
+
+	rec = request(http.MethodPost, "/login", url.Values{"name": {"Frau Muster"}, "passwort": {"falsch123"}})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Name oder Passwort ist falsch")
+
+	rec = request(http.MethodGet, "/login?next="+url.QueryEscape(`"><script>alert(1)</script>`), nil)
+	assert.NotContains(t, rec.Body.String(), "<script>alert(1)")
+	assert.Contains(t, rec.Body.String(), `value="/exams"`)
+	rec = request(http.MethodPost, "/login", url.Values{"name": {`"><script>alert(2)</script>`}, "passwort": {"falsch123"}, "next": {`/"><script>alert(3)</script>`}})
+	assert.NotContains(t, rec.Body.String(), "<script>alert(")
+	assert.Contains(t, rec.Body.String(), `value="/&#34;&gt;&lt;script&gt;alert(3)&lt;/script&gt;"`)
+
+	rec = request(http.MethodPost, "/login", url.Values{"name": {"frau muster"}, "passwort": {"geheim123"}, "next": {"//example.com"}})
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/exams", rec.Header().Get(echo.HeaderLocation))
+	cookies := rec.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+
+	rec = request(http.MethodGet, "/exams", nil, cookies...)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Abmelden")
+
+	rec = request(http.MethodPost, "/logout", nil, cookies...)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	rec = request(http.MethodGet, "/exams", nil, cookies...)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+}
+
+func TestLoginAPI(t *testing.T) {
+	jsonStore, err := model.NewJSONStore(filepath.Join(t.TempDir(), "bewertungen.json"))
+	assert.NoError(t, err)
+	konto, err := model.NewKonto("Frau Muster", "geheim123")
+	assert.NoError(t, err)
+	_, err = jsonStore.SaveKonto(konto)
+	assert.NoError(t, err)
+	e := echo.New()
+	New(Options{Store: jsonStore}).Register(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/exams", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get(echo.HeaderWWWAuthenticate))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/exams", nil)
+	req.SetBasicAuth("Frau Muster", "geheim123")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/toggle/1", nil)
+	req.Header.Set("HX-Request", "true")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "/login", rec.Header().Get("HX-Redirect"))
+}
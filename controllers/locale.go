@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Locale describes how numbers are written for the user.
+type Locale struct {
+	Decimal   string
+	Thousands string
+}
+
+var (
+	localeDE = Locale{Decimal: ",", Thousands: "."}
+	localeEN = Locale{Decimal: ".", Thousands: ","}
+)
+
+// LocaleByName returns the number format "de" (1.234,5) or "en" (1,234.5).
+func LocaleByName(name string) (Locale, error) {
+	switch name {
+	case "de":
+		return localeDE, nil
+	case "en":
+		return localeEN, nil
+	}
+	return Locale{}, fmt.Errorf("unbekanntes Zahlenformat %q", name)
+}
+
+// parseNumber reads a number written with either a decimal comma or a
+// decimal point. If both occur, the last one is the decimal separator and
+// the other one groups thousands, so "1.234,5" and "1,234.5" are both
+// accepted. A separator that occurs more than once groups thousands.
+func parseNumber(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	decimal := strings.LastIndexAny(value, ",.")
+	if decimal >= 0 {
+		separator := value[decimal : decimal+1]
+		other := "."
+		if separator == "." {
+			other = ","
+		}
+		switch {
+		case strings.Contains(value, other):
+			value = strings.ReplaceAll(value, other, "")
+		case strings.Count(value, separator) > 1:
+			value = strings.ReplaceAll(value, separator, "")
+		}
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// FormatNumber formats the number with the given number of decimals, or
+// with as many as needed if decimals is negative.
+func (l Locale) FormatNumber(number float64, decimals int) string {
+	formatted := strconv.FormatFloat(number, 'f', decimals, 64)
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign, formatted = "-", formatted[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(formatted, ".")
+
+	var grouped strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteString(l.Thousands)
+		}
+		grouped.WriteRune(digit)
+	}
+	if hasFraction {
+		return sign + grouped.String() + l.Decimal + fraction
+	}
+	return sign + grouped.String()
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		value  string
+		number float64
+	}{
+		{"12", 12},
+		{"12,5", 12.5},
+		{"12.5", 12.5},
+		{" 0,25 ", 0.25},
+		{"1.234,5", 1234.5},
+		{"1,234.5", 1234.5},
+		{"1.234.567", 1234567},
+		{"-3,5", -3.5},
+	}
+	for _, tt := range tests {
+		number, err := parseNumber(tt.value)
+		if assert.NoError(t, err, tt.value) {
+			assert.Equal(t, tt.number, number, tt.value)
+		}
+	}
+
+	_, err := parseNumber("12,5x")
+	assert.Error(t, err)
+}
+
+func TestFormatNumber(t *testing.T) {
+	assert.Equal(t, "12,50", localeDE.FormatNumber(12.5, 2))
+	assert.Equal(t, "12,5", localeDE.FormatNumber(12.5, -1))
+	assert.Equal(t, "1.234.567,00", localeDE.FormatNumber(1234567, 2))
+	assert.Equal(t, "-1.000", localeDE.FormatNumber(-1000, -1))
+	assert.Equal(t, "1,234.50", localeEN.FormatNumber(1234.5, 2))
+	assert.Equal(t, "100", localeDE.FormatNumber(100, 0))
+}
+
+func TestLocaleByName(t *testing.T) {
+	l, err := LocaleByName("en")
+	assert.NoError(t, err)
+	assert.Equal(t, localeEN, l)
+	_, err = LocaleByName("fr")
+	assert.Error(t, err)
+}
@@ -0,0 +1,419 @@
+package controllers
+
+import (
+	"errors"
+	"html"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"echoTest/model"
+
+	"github.com/chasefleming/elem-go"
+	"github.com/chasefleming/elem-go/attrs"
+	"github.com/chasefleming/elem-go/htmx"
+	"github.com/labstack/echo/v4"
+)
+
+// examNotenschluessel returns the scale selected by the exam, falling back
+// to the default scale of the Controller if it no longer exists.
+func (h *Controller) examNotenschluessel(exam model.Exam) (model.Notenschluessel, error) {
+	notenschluessel, err := h.store.LoadNotenschluessel(exam.NotenschluesselID)
+	if errors.Is(err, model.ErrNotFound) {
+		return h.notenschluessel, nil
+	}
+	return notenschluessel, err
+}
+
+// recomputeExam recalculates and stores every Bewertung of the exam.
+func (h *Controller) recomputeExam(exam model.Exam) error {
+	notenschluessel, err := h.examNotenschluessel(exam)
+	if err != nil {
+		return err
+	}
+	bewertungen, err := h.store.List(exam.ID)
+	if err != nil {
+		return err
+	}
+	for _, bewertung := range bewertungen {
+		if err := h.store.Update(model.Bewerte(exam, notenschluessel, bewertung)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Controller) renderNotenschluesselListRoute(c echo.Context) error {
+	list, err := h.store.ListNotenschluessel()
+	if err != nil {
+		return err
+	}
+	return c.HTML(http.StatusOK, renderNotenschluesselList(list))
+}
+
+func (h *Controller) addNotenschluesselRoute(c echo.Context) error {
+	name := c.FormValue("name")
+	if name == "" {
+		return c.Redirect(http.StatusSeeOther, "/scales")
+	}
+	notenschluessel, err := h.store.SaveNotenschluessel(model.Notenschluessel{
+		Name:   name,
+		Stufen: h.notenschluessel.Stufen,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Redirect(http.StatusSeeOther, notenschluesselURL(notenschluessel))
+}
+
+func (h *Controller) renderNotenschluesselRoute(c echo.Context) error {
+	notenschluessel, err := h.loadNotenschluessel(c)
+	if err != nil {
+		return err
+	}
+	return c.HTML(http.StatusOK, renderNotenschluessel(notenschluessel, notenstufeInputs(h.locale, notenschluessel.Stufen), nil))
+}
+
+// updateNotenschluesselRoute stores the edited scale and recalculates all
+// exams that use it.
+func (h *Controller) updateNotenschluesselRoute(c echo.Context) error {
+	notenschluessel, err := h.loadNotenschluessel(c)
+	if err != nil {
+		return err
+	}
+	if name := c.FormValue("name"); name != "" {
+		notenschluessel.Name = name
+	}
+	stufen, inputs, fieldErrors := parseNotenstufen(c)
+	if len(fieldErrors) > 0 {
+		return c.HTML(http.StatusUnprocessableEntity, renderNotenschluessel(notenschluessel, inputs, fieldErrors))
+	}
+	notenschluessel.Stufen = stufen
+	if err := h.store.UpdateNotenschluessel(notenschluessel); err != nil {
+		return err
+	}
+
+	exams, err := h.store.ListExams()
+	if err != nil {
+		return err
+	}
+	for _, exam := range exams {
+		if exam.NotenschluesselID != notenschluessel.ID {
+			continue
+		}
+		if err := h.recomputeExam(exam); err != nil {
+			return err
+		}
+	}
+	return c.Redirect(http.StatusSeeOther, notenschluesselURL(notenschluessel))
+}
+
+func (h *Controller) deleteNotenschluesselRoute(c echo.Context) error {
+	notenschluessel, err := h.loadNotenschluessel(c)
+	if err != nil {
+		return err
+	}
+	exams, err := h.store.ListExams()
+	if err != nil {
+		return err
+	}
+	for _, exam := range exams {
+		if exam.NotenschluesselID == notenschluessel.ID {
+			return echo.NewHTTPError(http.StatusConflict, "Der Notenschlüssel wird von "+exam.Titel+" verwendet")
+		}
+	}
+	if err := h.store.DeleteNotenschluessel(notenschluessel.ID); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *Controller) notenstufeInputRoute(c echo.Context) error {
+	return c.HTML(http.StatusOK, createNotenstufeInputNode(-1, notenstufeInput{}, nil).Render())
+}
+
+// loadNotenschluessel returns the scale addressed by the :id route parameter.
+func (h *Controller) loadNotenschluessel(c echo.Context) (model.Notenschluessel, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return model.Notenschluessel{}, echo.NewHTTPError(http.StatusNotFound, "Notenschlüssel nicht gefunden")
+	}
+	notenschluessel, err := h.store.LoadNotenschluessel(id)
+	if errors.Is(err, model.ErrNotFound) {
+		return model.Notenschluessel{}, echo.NewHTTPError(http.StatusNotFound, "Notenschlüssel nicht gefunden")
+	}
+	return notenschluessel, err
+}
+
+func notenschluesselURL(notenschluessel model.Notenschluessel) string {
+	return "/scales/" + strconv.Itoa(notenschluessel.ID)
+}
+
+// notenstufeInput is one row of the scale form as entered by the user.
+type notenstufeInput struct {
+	Name    string
+	Wert    string
+	Bis     string
+	Defizit bool
+}
+
+// notenstufeInputs returns the form rows that represent the Stufen.
+func notenstufeInputs(locale Locale, stufen []model.Notenstufe) []notenstufeInput {
+	inputs := make([]notenstufeInput, len(stufen))
+	for i, stufe := range stufen {
+		inputs[i] = notenstufeInput{
+			Name:    stufe.Note.Name,
+			Wert:    locale.FormatNumber(stufe.Note.Wert, -1),
+			Bis:     locale.FormatNumber(stufe.Bis, -1),
+			Defizit: stufe.Defizit,
+		}
+	}
+	return inputs
+}
+
+// noteField is the key of the field errors of the input name in the Stufe
+// at index i.
+func noteField(name string, i int) string {
+	return name + "_" + strconv.Itoa(i)
+}
+
+// parseNotenstufen reads the rows rendered by createNotenstufeInputNode and
+// returns the Stufen sorted by Bis. Empty rows are skipped, a missing upper
+// bound means 100 %. The rows are returned as entered to show them again
+// together with the field errors, which are keyed by noteField or
+// "stufen" for the scale as a whole.
+func parseNotenstufen(c echo.Context) ([]model.Notenstufe, []notenstufeInput, FieldErrors) {
+	form, _ := c.FormParams()
+	namen := form["note_name"]
+	werte := form["note_wert"]
+	grenzen := form["note_bis"]
+	defizite := form["note_defizit"]
+	value := func(values []string, i int) string {
+		if i < len(values) {
+			return strings.TrimSpace(values[i])
+		}
+		return ""
+	}
+
+	var stufen []model.Notenstufe
+	var inputs []notenstufeInput
+	fieldErrors := FieldErrors{}
+	grenzenSeen := map[float64]bool{}
+	for i := range namen {
+		input := notenstufeInput{
+			Name:    value(namen, i),
+			Wert:    value(werte, i),
+			Bis:     value(grenzen, i),
+			Defizit: value(defizite, i) == "ja",
+		}
+		if input.Name == "" && input.Wert == "" && input.Bis == "" {
+			continue
+		}
+		row := len(inputs)
+		inputs = append(inputs, input)
+
+		stufe := model.Notenstufe{Note: model.Note{Name: input.Name}, Bis: 100, Defizit: input.Defizit}
+		if input.Name == "" {
+			fieldErrors[noteField("note_name", row)] = "Bitte eine Note eingeben"
+		}
+		if input.Wert == "" {
+			fieldErrors[noteField("note_wert", row)] = "Bitte einen Wert eingeben"
+		} else if wert, err := parseNumber(input.Wert); err != nil || math.IsNaN(wert) || math.IsInf(wert, 0) {
+			fieldErrors[noteField("note_wert", row)] = "Keine gültige Zahl"
+		} else {
+			stufe.Note.Wert = wert
+		}
+		if input.Bis != "" {
+			bis, err := parseNumber(input.Bis)
+			switch {
+			case err != nil || math.IsNaN(bis):
+				fieldErrors[noteField("note_bis", row)] = "Keine gültige Zahl"
+			case bis < 0 || bis > 100:
+				fieldErrors[noteField("note_bis", row)] = "Zwischen 0 und 100 Prozent"
+			default:
+				stufe.Bis = bis
+			}
+		}
+		if fieldErrors[noteField("note_bis", row)] == "" {
+			if grenzenSeen[stufe.Bis] {
+				fieldErrors[noteField("note_bis", row)] = "Diese Grenze hat schon eine andere Stufe"
+			}
+			grenzenSeen[stufe.Bis] = true
+		}
+		stufen = append(stufen, stufe)
+	}
+	if len(inputs) == 0 {
+		fieldErrors["stufen"] = "Mindestens eine Stufe ist erforderlich"
+	}
+
+	sort.SliceStable(stufen, func(i, j int) bool {
+		return stufen[i].Bis < stufen[j].Bis
+	})
+	return stufen, inputs, fieldErrors
+}
+
+func createNotenschluesselNode(notenschluessel model.Notenschluessel) elem.Node {
+	return elem.Tr(nil,
+		elem.Td(nil, elem.A(attrs.Props{attrs.Href: notenschluesselURL(notenschluessel)}, elem.Text(html.EscapeString(notenschluessel.Name)))),
+		elem.Td(nil, elem.Text(strconv.Itoa(len(notenschluessel.Stufen)))),
+		elem.Td(nil,
+			elem.Button(attrs.Props{
+				attrs.Class:    "button is-small is-danger is-light",
+				htmx.HXDelete:  notenschluesselURL(notenschluessel),
+				htmx.HXConfirm: html.EscapeString("Notenschlüssel " + notenschluessel.Name + " löschen?"),
+				htmx.HXTarget:  "closest tr",
+				htmx.HXSwap:    "outerHTML",
+			},
+				elem.Text("Löschen"),
+			),
+		),
+	)
+}
+
+// createNotenstufeInputNode renders the inputs for one Stufe. index is
+// the position of the row in the form, used to look up its field errors.
+func createNotenstufeInputNode(index int, input notenstufeInput, fieldErrors FieldErrors) elem.Node {
+	return elem.Div(attrs.Props{attrs.Class: "tile is-ancestor"},
+		elem.Div(attrs.Props{attrs.Class: "tile field is-parent is-vertical"},
+			createInputNode("input is-child", "note_name", "Note, z.B. 2+", input.Name, fieldErrors[noteField("note_name", index)])...,
+		),
+		elem.Div(attrs.Props{attrs.Class: "tile field is-parent is-vertical"},
+			createInputNode("input is-child", "note_wert", "Wert, z.B. 1.7", input.Wert, fieldErrors[noteField("note_wert", index)])...,
+		),
+		elem.Div(attrs.Props{attrs.Class: "tile field is-parent is-vertical"},
+			createInputNode("input is-child", "note_bis", "bis Prozent", input.Bis, fieldErrors[noteField("note_bis", index)])...,
+		),
+		elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
+			elem.Div(attrs.Props{attrs.Class: "select is-child"},
+				elem.Select(attrs.Props{attrs.Name: "note_defizit"},
+					elem.Option(attrs.Props{attrs.Value: "nein", attrs.Selected: strconv.FormatBool(!input.Defizit)}, elem.Text("bestanden")),
+					elem.Option(attrs.Props{attrs.Value: "ja", attrs.Selected: strconv.FormatBool(input.Defizit)}, elem.Text("Defizit")),
+				),
+			),
+		),
+	)
+}
+
+// createNotenschluesselSelectNode renders a select for the exam forms with
+// the scale of the given ID preselected.
+func createNotenschluesselSelectNode(list []model.Notenschluessel, selectedID int) elem.Node {
+	return elem.Div(attrs.Props{attrs.Class: "select"},
+		elem.Select(attrs.Props{attrs.Name: "notenschluessel"},
+			elem.TransformEach(list, func(notenschluessel model.Notenschluessel) elem.Node {
+				return elem.Option(attrs.Props{
+					attrs.Value:    strconv.Itoa(notenschluessel.ID),
+					attrs.Selected: strconv.FormatBool(notenschluessel.ID == selectedID),
+				}, elem.Text(html.EscapeString(notenschluessel.Name)))
+			})...,
+		),
+	)
+}
+
+func renderNotenschluesselList(list []model.Notenschluessel) string {
+	bodyContent := elem.Div(attrs.Props{attrs.Class: "container is-widescreen"},
+		elem.Div(attrs.Props{attrs.Class: "card tile is-vertical is-ancestor"},
+			elem.Header(attrs.Props{attrs.Class: "card-header"},
+				elem.P(attrs.Props{attrs.Class: "card-header-title"}, elem.Text("Notenschlüssel"))),
+			elem.Div(attrs.Props{attrs.Class: "card-content"},
+				elem.Div(attrs.Props{attrs.Class: "content tile is-parent is-vertical gap"},
+					elem.Form(attrs.Props{attrs.Method: "post", attrs.Action: "/scales"},
+						elem.Div(attrs.Props{attrs.Class: "tile is-ancestor"},
+							elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
+								elem.Input(attrs.Props{
+									attrs.Type:        "text",
+									attrs.Name:        "name",
+									attrs.Class:       "input is-child",
+									attrs.Placeholder: "Name",
+								},
+								),
+							),
+							elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
+								elem.Button(
+									attrs.Props{
+										attrs.Type:  "submit",
+										attrs.Class: "button tile is-child",
+									},
+									elem.Text("Anlegen"),
+								),
+							),
+						),
+					),
+					elem.Div(attrs.Props{attrs.Class: "table-container"},
+						elem.Table(attrs.Props{attrs.Class: "table is-hoverable"},
+							elem.THead(nil,
+								elem.Tr(nil,
+									elem.Th(nil, elem.Text("Name")),
+									elem.Th(nil, elem.Text("Stufen")),
+									elem.Th(nil),
+								),
+							),
+							elem.TBody(nil,
+								elem.TransformEach(list, createNotenschluesselNode)...),
+						),
+					),
+				),
+			),
+		),
+	)
+
+	return renderPage(bodyContent)
+}
+
+// renderNotenschluessel renders the form of the scale with the rows as
+// entered and the messages of fieldErrors after a failed submission.
+func renderNotenschluessel(notenschluessel model.Notenschluessel, inputs []notenstufeInput, fieldErrors FieldErrors) string {
+	var stufeInputs []elem.Node
+	for i, input := range inputs {
+		stufeInputs = append(stufeInputs, createNotenstufeInputNode(i, input, fieldErrors))
+	}
+
+	bodyContent := elem.Div(attrs.Props{attrs.Class: "container is-widescreen"},
+		elem.Div(attrs.Props{attrs.Class: "card tile is-vertical is-ancestor"},
+			elem.Header(attrs.Props{attrs.Class: "card-header"},
+				elem.P(attrs.Props{attrs.Class: "card-header-title"}, elem.Text(html.EscapeString(notenschluessel.Name)))),
+			elem.Div(attrs.Props{attrs.Class: "card-content"},
+				elem.Div(attrs.Props{attrs.Class: "content tile is-parent is-vertical gap"},
+					elem.P(nil, elem.Text("Jede Note gilt bis einschließlich der angegebenen Prozentzahl. Die Stufen werden beim Speichern nach Prozent sortiert. Defizite zählen für die Drittelregel.")),
+					elem.Form(attrs.Props{attrs.Method: "post", attrs.Action: notenschluesselURL(notenschluessel)},
+						elem.Div(attrs.Props{attrs.Class: "field"},
+							elem.Input(attrs.Props{
+								attrs.Type:        "text",
+								attrs.Name:        "name",
+								attrs.Class:       "input",
+								attrs.Placeholder: "Name",
+								attrs.Value:       html.EscapeString(notenschluessel.Name),
+							},
+							),
+						),
+						elem.Div(attrs.Props{attrs.ID: "stufen"}, stufeInputs...),
+						elem.If[elem.Node](fieldErrors["stufen"] != "",
+							elem.P(attrs.Props{attrs.Class: "help is-danger"}, elem.Text(html.EscapeString(fieldErrors["stufen"]))),
+							elem.None(),
+						),
+						elem.Div(attrs.Props{attrs.Class: "buttons"},
+							elem.Button(attrs.Props{
+								attrs.Type:    "button",
+								attrs.Class:   "button",
+								htmx.HXGet:    "/scales/stufe",
+								htmx.HXTarget: "#stufen",
+								htmx.HXSwap:   "beforeend",
+							},
+								elem.Text("Stufe hinzufügen"),
+							),
+							elem.Button(attrs.Props{
+								attrs.Type:  "submit",
+								attrs.Class: "button is-primary",
+							},
+								elem.Text("Speichern"),
+							),
+						),
+					),
+				),
+			),
+		),
+	)
+
+	return renderPage(bodyContent)
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"echoTest/model"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateNotenschluesselRoute(t *testing.T) {
+	notenschluessel, err := testController.store.SaveNotenschluessel(model.Notenschluessel{
+		Name:   "Eigener",
+		Stufen: model.StandardNotenschluessel.Stufen,
+	})
+	assert.NoError(t, err)
+	t.Cleanup(func() { testController.store.DeleteNotenschluessel(notenschluessel.ID) })
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, notenschluesselURL(notenschluessel), strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(strconv.Itoa(notenschluessel.ID))
+		assert.NoError(t, testController.updateNotenschluesselRoute(c))
+		return rec
+	}
+
+	rec := post(url.Values{"note_name": {""}, "note_wert": {""}, "note_bis": {""}})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Mindestens eine Stufe ist erforderlich")
+
+	rec = post(url.Values{
+		"note_name": {"6", "5", "1"},
+		"note_wert": {"6", "x", "1"},
+		"note_bis":  {"50", "50", "120"},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Keine gültige Zahl")
+	assert.Contains(t, rec.Body.String(), "Diese Grenze hat schon eine andere Stufe")
+	assert.Contains(t, rec.Body.String(), "Zwischen 0 und 100 Prozent")
+	assert.Contains(t, rec.Body.String(), `value="120"`)
+
+	saved, err := testController.store.LoadNotenschluessel(notenschluessel.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.StandardNotenschluessel.Stufen, saved.Stufen)
+
+	rec = post(url.Values{
+		"note_name":    {"1", "6"},
+		"note_wert":    {"1", "6"},
+		"note_bis":     {"", "49,5"},
+		"note_defizit": {"nein", "ja"},
+	})
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	saved, err = testController.store.LoadNotenschluessel(notenschluessel.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Notenstufe{
+		{Note: model.Note{Name: "6", Wert: 6}, Bis: 49.5, Defizit: true},
+		{Note: model.Note{Name: "1", Wert: 1}, Bis: 100},
+	}, saved.Stufen)
+}
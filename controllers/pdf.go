@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	_ "embed"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"echoTest/model"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// The DejaVu fonts cover umlauts and most accented Latin letters. They are
+// free to redistribute under the Bitstream Vera license.
+var (
+	//go:embed fonts/DejaVuSansCondensed.ttf
+	pdfFontRegular []byte
+	//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+	pdfFontBold []byte
+	//go:embed fonts/DejaVuSansCondensed-Oblique.ttf
+	pdfFontItalic []byte
+)
+
+const (
+	pdfFont      = "DejaVu"
+	pdfMargin    = 10.0
+	pdfRowHeight = 6.0
+	pdfNameWidth = 40.0
+	// pdfFooterHeight is kept free at the bottom of every page.
+	pdfFooterHeight = 10.0
+)
+
+// pdfExporter leaves the Bewertungen that are not gewertet out of the
+// table. With Ausgeschlossene they are listed below it.
+type pdfExporter struct {
+	options ExportOptions
+}
+
+func (pdfExporter) ContentType() string { return "application/pdf" }
+
+func (pdfExporter) Extension() string { return "pdf" }
+
+func (p pdfExporter) Export(w io.Writer, exam model.Exam, bewertungen []model.Bewertung) error {
+	return newPDFReport(p.options.Locale, exam, p.options.Notenschluessel, bewertungen, p.options.Ausgeschlossene, p.options.Erstellt).Output(w)
+}
+
+// newPDF returns an A4 document with the embedded fonts. orientation is "P"
+// or "L".
+func newPDF(orientation string) *gofpdf.Fpdf {
+	pdf := gofpdf.New(orientation, "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.AddUTF8FontFromBytes(pdfFont, "", pdfFontRegular)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", pdfFontBold)
+	pdf.AddUTF8FontFromBytes(pdfFont, "I", pdfFontItalic)
+	return pdf
+}
+
+// pdfReport lays out the grade report of an exam on landscape A4 pages.
+type pdfReport struct {
+	pdf    *gofpdf.Fpdf
+	exam   model.Exam
+	locale Locale
+	// width is the width of every column except the names.
+	width float64
+	// inTable repeats the table header on every new page.
+	inTable bool
+}
+
+// newPDFReport renders the report with the exam details, a table with all
+// computed columns of the gewertete Bewertungen, a summary of them and lines
+// for the signatures. With ausgeschlossene the other Bewertungen are listed
+// with their Grund below the table. now is printed in the footer.
+func newPDFReport(locale Locale, exam model.Exam, notenschluessel model.Notenschluessel, bewertungen []model.Bewertung, ausgeschlossene bool, now time.Time) *gofpdf.Fpdf {
+	pdf := newPDF("L")
+	pdf.SetAutoPageBreak(false, pdfMargin)
+	pdf.AliasNbPages("")
+
+	pageWidth, _ := pdf.GetPageSize()
+	columns := 3*len(exam.Sections) + 2
+	report := &pdfReport{
+		pdf:    pdf,
+		exam:   exam,
+		locale: locale,
+		width:  (pageWidth - 2*pdfMargin - 2*pdfNameWidth) / float64(columns),
+	}
+	pdf.SetHeaderFunc(report.header)
+	pdf.SetFooterFunc(func() { report.footer(now) })
+
+	pdf.AddPage()
+	report.title(notenschluessel)
+	report.inTable = true
+	report.tableHeader()
+	var excluded []model.Bewertung
+	for _, bewertung := range bewertungen {
+		if !bewertung.Gewertet {
+			excluded = append(excluded, bewertung)
+			continue
+		}
+		report.row(bewertung)
+	}
+	report.inTable = false
+	if ausgeschlossene && len(excluded) > 0 {
+		report.excluded(excluded)
+	}
+	statistik := berechneStatistik(exam, notenschluessel, bewertungen)
+	report.summary(len(bewertungen), statistik)
+	report.diagramme(diagramme(statistik))
+	report.signatures()
+	return pdf
+}
+
+// ensureSpace starts a new page if less than height is left on this one.
+func (r *pdfReport) ensureSpace(height float64) {
+	_, pageHeight := r.pdf.GetPageSize()
+	if r.pdf.GetY()+height > pageHeight-pdfMargin-pdfFooterHeight {
+		r.pdf.AddPage()
+	}
+}
+
+// cell prints text shortened to fit the width. A width of 0 extends the
+// cell to the right margin.
+func (r *pdfReport) cell(width float64, text, border, align string, fill bool) {
+	if width > 0 && r.pdf.GetStringWidth(text) > width-2 {
+		runes := []rune(text)
+		for len(runes) > 0 && r.pdf.GetStringWidth(string(runes)+"…") > width-2 {
+			runes = runes[:len(runes)-1]
+		}
+		text = string(runes) + "…"
+	}
+	r.pdf.CellFormat(width, pdfRowHeight, text, border, 0, align, fill, 0, "")
+}
+
+func (r *pdfReport) title(notenschluessel model.Notenschluessel) {
+	r.pdf.SetFont(pdfFont, "B", 16)
+	r.pdf.CellFormat(0, 10, r.exam.Titel, "", 1, "", false, 0, "")
+
+	var details []string
+	for _, detail := range []struct{ label, value string }{
+		{"Fach", r.exam.Fach},
+		{"Klasse", r.exam.Klasse},
+		{"Lehrkraft", r.exam.Lehrkraft},
+		{"Datum", formatDatum(r.exam.Datum)},
+		{"Notenschlüssel", notenschluessel.Name},
+	} {
+		if detail.value != "" {
+			details = append(details, detail.label+": "+detail.value)
+		}
+	}
+	r.pdf.SetFont(pdfFont, "", 10)
+	r.pdf.CellFormat(0, pdfRowHeight, strings.Join(details, "     "), "", 1, "", false, 0, "")
+	r.pdf.Ln(4)
+}
+
+// header repeats the title and the table header on every page after the
+// first one.
+func (r *pdfReport) header() {
+	if r.pdf.PageNo() == 1 {
+		return
+	}
+	r.pdf.SetFont(pdfFont, "I", 8)
+	r.pdf.CellFormat(0, pdfRowHeight, r.exam.Titel+" "+r.exam.Klasse, "", 1, "", false, 0, "")
+	if r.inTable {
+		r.tableHeader()
+	}
+}
+
+func (r *pdfReport) footer(now time.Time) {
+	r.pdf.SetY(-pdfMargin - pdfRowHeight)
+	r.pdf.SetFont(pdfFont, "I", 8)
+	r.pdf.CellFormat(100, pdfRowHeight, "Erstellt am "+formatDatum(now), "", 0, "L", false, 0, "")
+	r.pdf.CellFormat(0, pdfRowHeight, "Seite "+strconv.Itoa(r.pdf.PageNo())+" von {nb}", "", 0, "R", false, 0, "")
+}
+
+// tableHeader prints two rows: the names of the sections and below them
+// the computed columns of each.
+func (r *pdfReport) tableHeader() {
+	r.pdf.SetFont(pdfFont, "B", 9)
+	r.pdf.SetFillColor(230, 230, 230)
+	r.cell(pdfNameWidth, "Vorname", "LTR", "", true)
+	r.cell(pdfNameWidth, "Nachname", "LTR", "", true)
+	for _, section := range r.exam.Sections {
+		r.cell(3*r.width, section.Name, "1", "C", true)
+	}
+	r.cell(2*r.width, "Gesamt", "1", "C", true)
+	r.pdf.Ln(-1)
+
+	r.cell(pdfNameWidth, "", "LBR", "", true)
+	r.cell(pdfNameWidth, "", "LBR", "", true)
+	for range r.exam.Sections {
+		r.cell(r.width, "Punkte", "1", "C", true)
+		r.cell(r.width, "%", "1", "C", true)
+		r.cell(r.width, "Note", "1", "C", true)
+	}
+	r.cell(r.width, "%", "1", "C", true)
+	r.cell(r.width, "Note", "1", "C", true)
+	r.pdf.Ln(-1)
+	r.pdf.SetFont(pdfFont, "", 9)
+}
+
+func (r *pdfReport) row(bewertung model.Bewertung) {
+	r.ensureSpace(pdfRowHeight)
+	r.cell(pdfNameWidth, bewertung.Vorname, "1", "", false)
+	r.cell(pdfNameWidth, bewertung.Nachname, "1", "", false)
+	for _, result := range bewertung.Sections {
+		r.cell(r.width, r.locale.FormatNumber(result.Punkte, 2), "1", "R", false)
+		r.cell(r.width, r.locale.FormatNumber(result.Prozent, 2), "1", "R", false)
+		r.cell(r.width, result.Note.Name, "1", "C", false)
+	}
+	r.cell(r.width, r.locale.FormatNumber(bewertung.GesamtProzent, 2), "1", "R", false)
+	r.cell(r.width, bewertung.GesamtNote.Name, "1", "C", false)
+	r.pdf.Ln(-1)
+}
+
+// excluded lists the Bewertungen that are not gewertet with their Grund.
+func (r *pdfReport) excluded(bewertungen []model.Bewertung) {
+	r.ensureSpace(8 + 2*pdfRowHeight)
+	r.pdf.Ln(6)
+	r.pdf.SetFont(pdfFont, "B", 11)
+	r.pdf.CellFormat(0, 8, "Nicht gewertet", "", 1, "", false, 0, "")
+	r.pdf.SetFont(pdfFont, "", 9)
+	for _, bewertung := range bewertungen {
+		r.ensureSpace(pdfRowHeight)
+		r.cell(pdfNameWidth, bewertung.Vorname, "1", "", false)
+		r.cell(pdfNameWidth, bewertung.Nachname, "1", "", false)
+		r.cell(2*pdfNameWidth, bewertung.Grund, "1", "", false)
+		r.pdf.Ln(-1)
+	}
+}
+
+// summary prints the averages and the Notenspiegel, a row of Noten with
+// the number of students who got each below it.
+func (r *pdfReport) summary(total int, statistik Statistik) {
+	r.ensureSpace(10 + 4*pdfRowHeight)
+	r.pdf.Ln(6)
+	r.pdf.SetFont(pdfFont, "B", 11)
+	r.pdf.CellFormat(0, 8, "Zusammenfassung", "", 1, "", false, 0, "")
+
+	r.pdf.SetFont(pdfFont, "", 10)
+	r.cell(80, "Gewertete Bewertungen: "+strconv.Itoa(statistik.Anzahl)+" von "+strconv.Itoa(total), "", "", false)
+	if statistik.Anzahl > 0 {
+		r.cell(0, "Durchschnitt: "+r.locale.FormatNumber(statistik.DurchschnittProzent, 2)+" %, Note "+r.locale.FormatNumber(statistik.Durchschnitt, 2), "", "", false)
+	}
+	r.pdf.Ln(-1)
+	r.pdf.Ln(2)
+
+	r.pdf.SetFont(pdfFont, "B", 9)
+	r.cell(25, "Note", "1", "", true)
+	for _, haeufigkeit := range statistik.Notenspiegel {
+		r.cell(15, haeufigkeit.Note.Name, "1", "C", true)
+	}
+	r.pdf.Ln(-1)
+	r.pdf.SetFont(pdfFont, "", 9)
+	r.cell(25, "Anzahl", "1", "", false)
+	for _, haeufigkeit := range statistik.Notenspiegel {
+		r.cell(15, strconv.Itoa(haeufigkeit.Anzahl), "1", "C", false)
+	}
+	r.pdf.Ln(-1)
+}
+
+// diagramme draws the charts next to each other below the summary.
+func (r *pdfReport) diagramme(diagramme []Diagramm) {
+	const height, gap = 50.0, 8.0
+	pageWidth, _ := r.pdf.GetPageSize()
+	available := pageWidth - 2*pdfMargin
+	width := min(90, (available-gap*float64(len(diagramme)-1))/float64(len(diagramme)))
+
+	r.ensureSpace(height + 6)
+	r.pdf.Ln(6)
+	y := r.pdf.GetY()
+	for i, diagramm := range diagramme {
+		r.diagramm(pdfMargin+float64(i)*(width+gap), y, width, height, diagramm)
+	}
+	r.pdf.SetXY(pdfMargin, y+height)
+}
+
+// diagramm draws a bar chart into the given box with the title above and
+// the labels below the bars.
+func (r *pdfReport) diagramm(x, y, width, height float64, diagramm Diagramm) {
+	const titleHeight, labelHeight = 6.0, 5.0
+	r.pdf.SetFont(pdfFont, "B", 9)
+	r.pdf.SetXY(x, y)
+	r.pdf.CellFormat(width, titleHeight, diagramm.Titel, "", 0, "C", false, 0, "")
+
+	hoechste := diagramm.hoechsteAnzahl()
+	plotHeight := height - titleHeight - 2*labelHeight
+	baseline := y + height - labelHeight
+	step := width / float64(max(len(diagramm.Balken), 1))
+	r.pdf.SetDrawColor(74, 74, 74)
+	r.pdf.Line(x, baseline, x+width, baseline)
+	r.pdf.SetFillColor(72, 95, 199)
+	r.pdf.SetFont(pdfFont, "", 7)
+	for i, balken := range diagramm.Balken {
+		barHeight := plotHeight * float64(balken.Anzahl) / float64(hoechste)
+		left := x + float64(i)*step
+		if barHeight > 0 {
+			r.pdf.Rect(left+step*0.1, baseline-barHeight, step*0.8, barHeight, "F")
+			r.pdf.SetXY(left, baseline-barHeight-labelHeight)
+			r.pdf.CellFormat(step, labelHeight, strconv.Itoa(balken.Anzahl), "", 0, "C", false, 0, "")
+		}
+		r.pdf.SetXY(left, baseline)
+		r.pdf.CellFormat(step, labelHeight, balken.Label, "", 0, "C", false, 0, "")
+	}
+	r.pdf.SetDrawColor(0, 0, 0)
+}
+
+func (r *pdfReport) signatures() {
+	r.ensureSpace(25)
+	r.pdf.Ln(15)
+	pageWidth, _ := r.pdf.GetPageSize()
+	y := r.pdf.GetY()
+	r.pdf.Line(pdfMargin, y, pdfMargin+90, y)
+	r.pdf.Line(pageWidth-pdfMargin-90, y, pageWidth-pdfMargin, y)
+
+	lehrkraft := "Datum, Unterschrift Lehrkraft"
+	if r.exam.Lehrkraft != "" {
+		lehrkraft += " (" + r.exam.Lehrkraft + ")"
+	}
+	r.pdf.SetFont(pdfFont, "", 8)
+	r.cell(90, lehrkraft, "", "", false)
+	r.pdf.SetX(pageWidth - pdfMargin - 90)
+	r.cell(90, "Datum, Unterschrift Schulleitung", "", "", false)
+	r.pdf.Ln(-1)
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+
+	"echoTest/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPDFReportPagination(t *testing.T) {
+	exam := model.Exam{
+		Titel:     "Englischarbeit",
+		Klasse:    "7b",
+		Lehrkraft: "Frau Schmidt",
+		Sections: []model.Section{
+			{Name: "HV", Max: 20, Gewichtung: 50},
+			{Name: "LV", Max: 30, Gewichtung: 50},
+		},
+	}
+	var bewertungen []model.Bewertung
+	for i := 0; i < 40; i++ {
+		bewertungen = append(bewertungen, model.Bewerte(exam, model.StandardNotenschluessel, model.Bewertung{
+			Vorname:  "Schülerin mit einem sehr langen Vornamen",
+			Nachname: "Nummer " + strconv.Itoa(i),
+			Sections: []model.SectionResult{{Punkte: float64(i % 20)}, {Punkte: 25}},
+			Gewertet: true,
+		}))
+	}
+
+	pdf := newPDFReport(localeDE, exam, model.StandardNotenschluessel, bewertungen, false, time.Now())
+	assert.NoError(t, pdf.Error())
+	assert.Greater(t, pdf.PageCount(), 1)
+}
+
+func TestPDFReportUnicode(t *testing.T) {
+	exam := model.Exam{Titel: "Prüfung", Sections: []model.Section{{Name: "Hörverstehen", Max: 10, Gewichtung: 100}}}
+	bewertungen := []model.Bewertung{
+		model.Bewerte(exam, model.StandardNotenschluessel, model.Bewertung{Vorname: "Łukasz", Nachname: "Weiß", Sections: []model.SectionResult{{Punkte: 7}}}),
+	}
+
+	var out bytes.Buffer
+	pdf := newPDFReport(localeDE, exam, model.StandardNotenschluessel, bewertungen, true, time.Now())
+	assert.NoError(t, pdf.Output(&out))
+	assert.Contains(t, out.String(), "/FontFile2")
+	assert.NotContains(t, out.String(), "Helvetica")
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"archive/zip"
+	"io"
+	"net/http"
+	"strconv"
+
+	"echoTest/model"
+
+	"github.com/chasefleming/elem-go"
+	"github.com/chasefleming/elem-go/attrs"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/labstack/echo/v4"
+)
+
+// exportRueckmeldeboegenRoute streams a Rückmeldebogen for every Bewertung
+// of an exam, either as one PDF with a page per student or, with
+// ?format=zip, as a ZIP archive with a PDF per student.
+func (h *Controller) exportRueckmeldeboegenRoute(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "pdf"
+	}
+	if format != "pdf" && format != "zip" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unbekanntes Format: "+format)
+	}
+	exam, err := h.loadExam(c)
+	if err != nil {
+		return err
+	}
+	bewertungen, err := h.store.List(exam.ID)
+	if err != nil {
+		return err
+	}
+	notenschluessel, err := h.examNotenschluessel(exam)
+	if err != nil {
+		return err
+	}
+
+	name := exportName(exam, h.now()) + "-Rueckmeldungen"
+	if format == "zip" {
+		return h.sendExport(c, name, "zip", "application/zip", func(w io.Writer) error {
+			return writeRueckmeldeboegenZip(w, h.locale, exam, notenschluessel, bewertungen)
+		})
+	}
+	return h.sendExport(c, name, "pdf", "application/pdf", func(w io.Writer) error {
+		pdf := newPDF("P")
+		for _, bewertung := range bewertungen {
+			addRueckmeldebogen(pdf, h.locale, exam, notenschluessel, bewertung)
+		}
+		return pdf.Output(w)
+	})
+}
+
+// writeRueckmeldeboegenZip writes a PDF per Bewertung named after the
+// student into a ZIP archive.
+func writeRueckmeldeboegenZip(w io.Writer, locale Locale, exam model.Exam, notenschluessel model.Notenschluessel, bewertungen []model.Bewertung) error {
+	archive := zip.NewWriter(w)
+	names := map[string]int{}
+	for _, bewertung := range bewertungen {
+		name := fileNamePart(bewertung.Nachname + "_" + bewertung.Vorname)
+		// Two students may only differ in characters replaced by fileNamePart
+		if names[name]++; names[name] > 1 {
+			name += "_" + strconv.Itoa(names[name])
+		}
+		part, err := archive.Create(name + ".pdf")
+		if err != nil {
+			return err
+		}
+		pdf := newPDF("P")
+		addRueckmeldebogen(pdf, locale, exam, notenschluessel, bewertung)
+		if err := pdf.Output(part); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// addRueckmeldebogen adds a page with the results of one student in every
+// section, the overall grade, the grading scale used and the comment of the
+// teacher.
+func addRueckmeldebogen(pdf *gofpdf.Fpdf, locale Locale, exam model.Exam, notenschluessel model.Notenschluessel, bewertung model.Bewertung) {
+	const rowHeight = 7.0
+	pdf.AddPage()
+
+	pdf.SetFont(pdfFont, "B", 16)
+	pdf.CellFormat(0, 10, "Rückmeldung: "+exam.Titel, "", 1, "", false, 0, "")
+	pdf.SetFont(pdfFont, "", 10)
+	for _, detail := range []struct{ label, value string }{
+		{"Fach", exam.Fach},
+		{"Klasse", exam.Klasse},
+		{"Datum", formatDatum(exam.Datum)},
+		{"Lehrkraft", exam.Lehrkraft},
+	} {
+		if detail.value != "" {
+			pdf.CellFormat(30, rowHeight-2, detail.label+":", "", 0, "", false, 0, "")
+			pdf.CellFormat(0, rowHeight-2, detail.value, "", 1, "", false, 0, "")
+		}
+	}
+	pdf.Ln(4)
+	pdf.SetFont(pdfFont, "B", 12)
+	pdf.CellFormat(0, rowHeight, bewertung.Vorname+" "+bewertung.Nachname, "", 1, "", false, 0, "")
+	pdf.Ln(2)
+
+	// Results per section
+	widths := []float64{60, 25, 25, 25, 25, 30}
+	pdf.SetFont(pdfFont, "B", 10)
+	pdf.SetFillColor(230, 230, 230)
+	for i, header := range []string{"Teil", "Punkte", "von", "Prozent", "Note", "Gewichtung"} {
+		pdf.CellFormat(widths[i], rowHeight, header, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFont(pdfFont, "", 10)
+	for i, section := range exam.Sections {
+		var result model.SectionResult
+		if i < len(bewertung.Sections) {
+			result = bewertung.Sections[i]
+		}
+		pdf.CellFormat(widths[0], rowHeight, section.Name, "1", 0, "", false, 0, "")
+		pdf.CellFormat(widths[1], rowHeight, locale.FormatNumber(result.Punkte, 2), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], rowHeight, locale.FormatNumber(section.Max, 2), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], rowHeight, locale.FormatNumber(result.Prozent, 2)+" %", "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], rowHeight, result.Note.Name, "1", 0, "C", false, 0, "")
+		pdf.CellFormat(widths[5], rowHeight, locale.FormatNumber(section.Gewichtung, 2)+" %", "1", 0, "R", false, 0, "")
+		pdf.Ln(-1)
+	}
+	pdf.SetFont(pdfFont, "B", 10)
+	pdf.CellFormat(widths[0]+widths[1]+widths[2], rowHeight, "Gesamt", "1", 0, "", true, 0, "")
+	pdf.CellFormat(widths[3], rowHeight, locale.FormatNumber(bewertung.GesamtProzent, 2)+" %", "1", 0, "R", true, 0, "")
+	pdf.CellFormat(widths[4], rowHeight, bewertung.GesamtNote.Name, "1", 0, "C", true, 0, "")
+	pdf.CellFormat(widths[5], rowHeight, "", "1", 0, "", true, 0, "")
+	pdf.Ln(-1)
+	if !bewertung.Gewertet {
+		pdf.SetFont(pdfFont, "I", 10)
+		pdf.CellFormat(0, rowHeight, "Diese Arbeit wird "+ausschlussText(bewertung)+".", "", 1, "", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	// Grading scale, the best Note first
+	pdf.SetFont(pdfFont, "B", 10)
+	pdf.CellFormat(0, rowHeight, "Notenschlüssel: "+notenschluessel.Name, "", 1, "", false, 0, "")
+	pdf.CellFormat(25, rowHeight-1, "Note", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(40, rowHeight-1, "Prozent", "1", 1, "C", true, 0, "")
+	pdf.SetFont(pdfFont, "", 9)
+	for i := len(notenschluessel.Stufen) - 1; i >= 0; i-- {
+		stufe := notenschluessel.Stufen[i]
+		bereich := "bis " + locale.FormatNumber(stufe.Bis, -1)
+		if i > 0 {
+			bereich = "über " + locale.FormatNumber(notenschluessel.Stufen[i-1].Bis, -1) + " " + bereich
+		}
+		pdf.CellFormat(25, rowHeight-1, stufe.Note.Name, "1", 0, "C", false, 0, "")
+		pdf.CellFormat(40, rowHeight-1, bereich, "1", 1, "C", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	if bewertung.Kommentar != "" {
+		pdf.SetFont(pdfFont, "B", 10)
+		pdf.CellFormat(0, rowHeight, "Kommentar", "", 1, "", false, 0, "")
+		pdf.SetFont(pdfFont, "", 10)
+		pdf.MultiCell(0, rowHeight-2, bewertung.Kommentar, "1", "", false)
+		pdf.Ln(6)
+	}
+
+	pdf.Ln(10)
+	y := pdf.GetY()
+	pdf.Line(pdfMargin, y, pdfMargin+80, y)
+	pdf.SetFont(pdfFont, "", 8)
+	pdf.CellFormat(80, rowHeight-2, "Unterschrift Erziehungsberechtigte", "", 1, "", false, 0, "")
+}
+
+func createRueckmeldeboegenLinkNode(exam model.Exam, format, label string) elem.Node {
+	return elem.A(attrs.Props{
+		attrs.Class:    "button",
+		attrs.Href:     examURL(exam) + "/export/students?format=" + format,
+		attrs.Download: "",
+	},
+		elem.Text(label),
+	)
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"echoTest/model"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportRueckmeldeboegenRoute(t *testing.T) {
+	exam := createTestExam(t)
+	for _, name := range []string{"Müller", "Weiß"} {
+		_, err := testController.store.Save(model.Bewerte(exam, model.StandardNotenschluessel, model.Bewertung{
+			ExamID:    exam.ID,
+			Vorname:   "Anna",
+			Nachname:  name,
+			Sections:  []model.SectionResult{{Punkte: 15}, {Punkte: 20}},
+			Kommentar: "Sehr sorgfältig gearbeitet.",
+		}))
+		assert.NoError(t, err)
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, examURL(exam)+"/export/students?format=zip", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(exam.ID))
+
+	assert.NoError(t, testController.exportRueckmeldeboegenRoute(c))
+	assert.Equal(t, "application/zip", rec.Header().Get(echo.HeaderContentType))
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if assert.NoError(t, err) && assert.Len(t, archive.File, 2) {
+		assert.Equal(t, "Müller_Anna.pdf", archive.File[0].Name)
+		assert.Equal(t, "Weiß_Anna.pdf", archive.File[1].Name)
+	}
+}
+
+func TestAddRueckmeldebogen(t *testing.T) {
+	exam := model.Exam{Sections: []model.Section{{Name: "HV", Max: 20, Gewichtung: 100}}}
+	pdf := newPDF("P")
+	for i := 0; i < 3; i++ {
+		addRueckmeldebogen(pdf, localeDE, exam, model.StandardNotenschluessel, model.Bewerte(exam, model.StandardNotenschluessel, model.Bewertung{
+			Sections: []model.SectionResult{{Punkte: float64(i)}},
+		}))
+	}
+	assert.NoError(t, pdf.Error())
+	assert.Equal(t, 3, pdf.PageCount())
+}
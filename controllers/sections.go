@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"html"
+	"net/http"
+	"strconv"
+
+	"echoTest/model"
+
+	"github.com/chasefleming/elem-go"
+	"github.com/chasefleming/elem-go/attrs"
+	"github.com/labstack/echo/v4"
+)
+
+// punkteField is the name of the form input holding the points of the
+// section at index i.
+func punkteField(i int) string {
+	return "punkte_" + strconv.Itoa(i)
+}
+
+// parseSections reads the section rows rendered by createSectionInputNode.
+// Rows without a name are skipped. For every section it also returns the
+// index the section had before, or -1 for a new one.
+func parseSections(c echo.Context) ([]model.Section, []int) {
+	form, _ := c.FormParams()
+	names := form["section_name"]
+	maxes := form["section_max"]
+	gewichtungen := form["section_gewichtung"]
+	indexes := form["section_index"]
+
+	var sections []model.Section
+	var origins []int
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		section := model.Section{Name: name}
+		if i < len(maxes) {
+			section.Max, _ = parseNumber(maxes[i])
+		}
+		if i < len(gewichtungen) {
+			section.Gewichtung, _ = parseNumber(gewichtungen[i])
+		}
+		origin := -1
+		if i < len(indexes) && indexes[i] != "" {
+			origin, _ = strconv.Atoi(indexes[i])
+		}
+		sections = append(sections, section)
+		origins = append(origins, origin)
+	}
+	return sections, origins
+}
+
+// validateSections returns a message describing why the sections cannot be
+// used for grading, or an empty string.
+func validateSections(sections []model.Section) string {
+	if len(sections) == 0 {
+		return "Mindestens ein Teil ist erforderlich"
+	}
+	for _, section := range sections {
+		if section.Max <= 0 {
+			return "Die Max-Punkte von " + section.Name + " müssen größer als 0 sein"
+		}
+	}
+	if !checkGewichtung(sections) {
+		return "Die Gewichtungen müssen zusammen 100 % ergeben"
+	}
+	return ""
+}
+
+// remapSections reorders the results of a Bewertung after the sections of
+// its exam changed. origins is the second result of parseSections.
+func remapSections(results []model.SectionResult, origins []int) []model.SectionResult {
+	remapped := make([]model.SectionResult, len(origins))
+	for i, origin := range origins {
+		if origin >= 0 && origin < len(results) {
+			remapped[i] = results[origin]
+		}
+	}
+	return remapped
+}
+
+func (h *Controller) sectionInputRoute(c echo.Context) error {
+	return c.HTML(http.StatusOK, createSectionInputNode(h.locale, -1, model.Section{}).Render())
+}
+
+// createSectionInputNode renders the inputs for one section. index is the
+// position of an existing section in its exam, or -1 for a new one.
+func createSectionInputNode(locale Locale, index int, section model.Section) elem.Node {
+	value := func(number float64) string {
+		if number == 0 {
+			return ""
+		}
+		return locale.FormatNumber(number, 2)
+	}
+
+	origin := ""
+	if index >= 0 {
+		origin = strconv.Itoa(index)
+	}
+
+	return elem.Div(attrs.Props{attrs.Class: "tile is-ancestor"},
+		elem.Input(attrs.Props{
+			attrs.Type:  "hidden",
+			attrs.Name:  "section_index",
+			attrs.Value: origin,
+		}),
+		elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
+			elem.Input(attrs.Props{
+				attrs.Class:       "input is-child",
+				attrs.Type:        "text",
+				attrs.Name:        "section_name",
+				attrs.Placeholder: "Teil, z.B. Grammatik",
+				attrs.Value:       html.EscapeString(section.Name),
+			},
+			),
+		),
+		elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
+			elem.Input(attrs.Props{
+				attrs.Class:       "input is-child",
+				attrs.Type:        "text",
+				attrs.Name:        "section_max",
+				attrs.Placeholder: "Max-Punkte",
+				attrs.Value:       value(section.Max),
+			},
+			),
+		),
+		elem.Div(attrs.Props{attrs.Class: "tile field is-parent"},
+			elem.Input(attrs.Props{
+				attrs.Class:       "input is-child",
+				attrs.Type:        "text",
+				attrs.Name:        "section_gewichtung",
+				attrs.Placeholder: "Gewichtung in %",
+				attrs.Value:       value(section.Gewichtung),
+			},
+			),
+		),
+	)
+}
+
+func createSectionsSummaryNode(locale Locale, sections []model.Section, notenschluessel model.Notenschluessel) elem.Node {
+	tags := elem.TransformEach(sections, func(section model.Section) elem.Node {
+		return elem.Span(attrs.Props{attrs.Class: "tag is-info is-light"},
+			elem.Text(html.EscapeString(section.Name)+": "+locale.FormatNumber(section.Max, 2)+" Punkte, "+locale.FormatNumber(section.Gewichtung, 2)+" %"),
+		)
+	})
+	tags = append(tags, elem.A(attrs.Props{
+		attrs.Class: "tag is-link is-light",
+		attrs.Href:  notenschluesselURL(notenschluessel),
+	}, elem.Text(html.EscapeString(notenschluessel.Name))))
+	return elem.Div(attrs.Props{attrs.Class: "tags"}, tags...)
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"html"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"echoTest/model"
+
+	"github.com/chasefleming/elem-go"
+	"github.com/chasefleming/elem-go/attrs"
+	"github.com/chasefleming/elem-go/htmx"
+	"github.com/labstack/echo/v4"
+)
+
+// Statistik summarises the gewertete Bewertungen of an exam.
+type Statistik struct {
+	Anzahl              int
+	DurchschnittProzent float64
+	// Durchschnitt, Median and Standardabweichung are computed from the
+	// Wert of the Gesamt-Noten.
+	Durchschnitt       float64
+	Median             float64
+	Standardabweichung float64
+	// Defizite counts the failing Gesamt-Noten, see Notenstufe.
+	Defizite     int
+	Notenspiegel []Haeufigkeit
+	Teile        []TeilStatistik
+}
+
+// Haeufigkeit counts how often a Note was given.
+type Haeufigkeit struct {
+	Note   model.Note
+	Anzahl int
+}
+
+// TeilStatistik holds the averages of one section of the exam.
+// Verteilung counts the percentages reached in steps of 10 %, the last
+// step includes 100 %.
+type TeilStatistik struct {
+	Name                string
+	DurchschnittPunkte  float64
+	DurchschnittProzent float64
+	Verteilung          [10]int
+}
+
+// berechneStatistik computes the Statistik of the Bewertungen. The
+// Notenspiegel lists every Note of the scale, the best one first.
+func berechneStatistik(exam model.Exam, notenschluessel model.Notenschluessel, bewertungen []model.Bewertung) Statistik {
+	var statistik Statistik
+	for i := len(notenschluessel.Stufen) - 1; i >= 0; i-- {
+		statistik.Notenspiegel = append(statistik.Notenspiegel, Haeufigkeit{Note: notenschluessel.Stufen[i].Note})
+	}
+	for _, section := range exam.Sections {
+		statistik.Teile = append(statistik.Teile, TeilStatistik{Name: section.Name})
+	}
+
+	var summeProzent float64
+	var werte []float64
+	for _, bewertung := range bewertungen {
+		if !bewertung.Gewertet {
+			continue
+		}
+		statistik.Anzahl++
+		summeProzent += bewertung.GesamtProzent
+		werte = append(werte, bewertung.GesamtNote.Wert)
+		if notenschluessel.Defizit(bewertung.GesamtNote) {
+			statistik.Defizite++
+		}
+		for i := range statistik.Notenspiegel {
+			if statistik.Notenspiegel[i].Note.Name == bewertung.GesamtNote.Name {
+				statistik.Notenspiegel[i].Anzahl++
+				break
+			}
+		}
+		for i, result := range bewertung.Sections {
+			if i < len(statistik.Teile) {
+				statistik.Teile[i].DurchschnittPunkte += result.Punkte
+				statistik.Teile[i].DurchschnittProzent += result.Prozent
+				bereich := min(max(int(result.Prozent/10), 0), len(statistik.Teile[i].Verteilung)-1)
+				statistik.Teile[i].Verteilung[bereich]++
+			}
+		}
+	}
+	if statistik.Anzahl == 0 {
+		return statistik
+	}
+
+	anzahl := float64(statistik.Anzahl)
+	statistik.DurchschnittProzent = summeProzent / anzahl
+	for i := range statistik.Teile {
+		statistik.Teile[i].DurchschnittPunkte /= anzahl
+		statistik.Teile[i].DurchschnittProzent /= anzahl
+	}
+
+	var summe float64
+	for _, wert := range werte {
+		summe += wert
+	}
+	statistik.Durchschnitt = summe / anzahl
+	var quadrate float64
+	for _, wert := range werte {
+		quadrate += (wert - statistik.Durchschnitt) * (wert - statistik.Durchschnitt)
+	}
+	statistik.Standardabweichung = math.Sqrt(quadrate / anzahl)
+
+	sort.Float64s(werte)
+	mitte := len(werte) / 2
+	statistik.Median = werte[mitte]
+	if len(werte)%2 == 0 {
+		statistik.Median = (werte[mitte-1] + werte[mitte]) / 2
+	}
+	return statistik
+}
+
+// DefizitAnteil returns the share of failing grades in percent.
+func (s Statistik) DefizitAnteil() float64 {
+	if s.Anzahl == 0 {
+		return 0
+	}
+	return 100 * float64(s.Defizite) / float64(s.Anzahl)
+}
+
+// Drittelregel reports whether more than a third of the grades are failing,
+// which requires the approval of the principal.
+func (s Statistik) Drittelregel() bool {
+	return 3*s.Defizite > s.Anzahl
+}
+
+// loadStatistik computes the Statistik of the exam from the store.
+func (h *Controller) loadStatistik(exam model.Exam) (Statistik, error) {
+	notenschluessel, err := h.examNotenschluessel(exam)
+	if err != nil {
+		return Statistik{}, err
+	}
+	bewertungen, err := h.store.List(exam.ID)
+	if err != nil {
+		return Statistik{}, err
+	}
+	return berechneStatistik(exam, notenschluessel, bewertungen), nil
+}
+
+// statistikChanged is the htmx event that reloads the statistics panel
+// after a row has changed.
+const statistikChanged = "statistikChanged"
+
+func (h *Controller) renderStatistikRoute(c echo.Context) error {
+	exam, err := h.loadExam(c)
+	if err != nil {
+		return err
+	}
+	statistik, err := h.loadStatistik(exam)
+	if err != nil {
+		return err
+	}
+	return c.HTML(http.StatusOK, createStatistikNode(h.locale, exam, statistik).Render())
+}
+
+// createStatistikNode renders the statistics panel, which reloads itself
+// when a response triggers statistikChanged.
+func createStatistikNode(locale Locale, exam model.Exam, statistik Statistik) elem.Node {
+	props := attrs.Props{
+		attrs.ID:       "statistik",
+		attrs.Class:    "box",
+		htmx.HXGet:     examURL(exam) + "/statistik",
+		htmx.HXTrigger: statistikChanged + " from:body",
+		htmx.HXSwap:    "outerHTML",
+	}
+	if statistik.Anzahl == 0 {
+		return elem.Div(props, elem.P(nil, elem.Text("Noch keine gewerteten Bewertungen.")))
+	}
+
+	kennzahl := func(label, value string) elem.Node {
+		return elem.Div(attrs.Props{attrs.Class: "level-item has-text-centered"},
+			elem.Div(nil,
+				elem.P(attrs.Props{attrs.Class: "heading"}, elem.Text(label)),
+				elem.P(attrs.Props{attrs.Class: "title is-5"}, elem.Text(value)),
+			),
+		)
+	}
+
+	noten := []elem.Node{elem.Th(nil, elem.Text("Note"))}
+	anzahlen := []elem.Node{elem.Th(nil, elem.Text("Anzahl"))}
+	for _, haeufigkeit := range statistik.Notenspiegel {
+		noten = append(noten, elem.Th(nil, elem.Text(html.EscapeString(haeufigkeit.Note.Name))))
+		anzahlen = append(anzahlen, elem.Td(nil, elem.Text(strconv.Itoa(haeufigkeit.Anzahl))))
+	}
+
+	return elem.Div(props,
+		elem.H2(attrs.Props{attrs.Class: "subtitle"}, elem.Text("Statistik")),
+		elem.Div(attrs.Props{attrs.Class: "level"},
+			kennzahl("Gewertet", strconv.Itoa(statistik.Anzahl)),
+			kennzahl("Durchschnitt", locale.FormatNumber(statistik.Durchschnitt, 2)),
+			kennzahl("Median", locale.FormatNumber(statistik.Median, 2)),
+			kennzahl("Standardabweichung", locale.FormatNumber(statistik.Standardabweichung, 2)),
+			kennzahl("Durchschnitt Prozent", locale.FormatNumber(statistik.DurchschnittProzent, 2)+" %"),
+			kennzahl("Anteil Defizite", locale.FormatNumber(statistik.DefizitAnteil(), 1)+" %"),
+		),
+		elem.If[elem.Node](statistik.Drittelregel(),
+			elem.Div(attrs.Props{attrs.Class: "notification is-warning is-light"},
+				elem.Text("Mehr als ein Drittel der Arbeiten ist mit einem Defizit bewertet. Die Arbeit muss von der Schulleitung genehmigt werden."),
+			),
+			elem.None(),
+		),
+		elem.Div(attrs.Props{attrs.Class: "table-container"},
+			elem.Table(attrs.Props{attrs.Class: "table is-narrow is-bordered"},
+				elem.Tr(nil, noten...),
+				elem.Tr(nil, anzahlen...),
+			),
+		),
+		elem.Div(attrs.Props{attrs.Class: "columns is-multiline"},
+			elem.TransformEach(diagramme(statistik), func(diagramm Diagramm) elem.Node {
+				return elem.Div(attrs.Props{attrs.Class: "column is-one-third"}, createDiagrammNode(diagramm))
+			})...,
+		),
+		elem.Table(attrs.Props{attrs.Class: "table is-narrow"},
+			elem.THead(nil,
+				elem.Tr(nil,
+					elem.Th(nil, elem.Text("Teil")),
+					elem.Th(nil, elem.Text("Ø Punkte")),
+					elem.Th(nil, elem.Text("Ø Prozent")),
+				),
+			),
+			elem.TBody(nil, elem.TransformEach(statistik.Teile, func(teil TeilStatistik) elem.Node {
+				return elem.Tr(nil,
+					elem.Td(nil, elem.Text(html.EscapeString(teil.Name))),
+					elem.Td(nil, elem.Text(locale.FormatNumber(teil.DurchschnittPunkte, 2))),
+					elem.Td(nil, elem.Text(locale.FormatNumber(teil.DurchschnittProzent, 2)+" %")),
+				)
+			})...),
+		),
+	)
+}
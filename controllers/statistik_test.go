@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"testing"
+
+	"echoTest/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBerechneStatistik(t *testing.T) {
+	exam := model.Exam{Sections: []model.Section{{Name: "HV", Max: 20, Gewichtung: 50}, {Name: "LV", Max: 30, Gewichtung: 50}}}
+	bewertungen := []model.Bewertung{
+		{GesamtProzent: 90, GesamtNote: model.Note{Name: "2", Wert: 2}, Sections: []model.SectionResult{{Punkte: 18, Prozent: 90}, {Punkte: 27, Prozent: 90}}, Gewertet: true},
+		{GesamtProzent: 60, GesamtNote: model.Note{Name: "4", Wert: 4}, Sections: []model.SectionResult{{Punkte: 12, Prozent: 60}, {Punkte: 18, Prozent: 60}}, Gewertet: true},
+		{GesamtProzent: 40, GesamtNote: model.Note{Name: "5", Wert: 5}, Sections: []model.SectionResult{{Punkte: 8, Prozent: 40}, {Punkte: 12, Prozent: 40}}, Gewertet: true},
+		{GesamtProzent: 10, GesamtNote: model.Note{Name: "6", Wert: 6}, Gewertet: false},
+	}
+	statistik := berechneStatistik(exam, model.StandardNotenschluessel, bewertungen)
+	assert.Equal(t, 3, statistik.Anzahl)
+	assert.InDelta(t, 63.33, statistik.DurchschnittProzent, 0.01)
+	assert.InDelta(t, 3.67, statistik.Durchschnitt, 0.01)
+	assert.Equal(t, 4.0, statistik.Median)
+	assert.InDelta(t, 1.25, statistik.Standardabweichung, 0.01)
+	assert.Equal(t, 1, statistik.Defizite)
+	assert.False(t, statistik.Drittelregel())
+	assert.Equal(t, []Haeufigkeit{
+		{Note: model.Note{Name: "1", Wert: 1}},
+		{Note: model.Note{Name: "2", Wert: 2}, Anzahl: 1},
+		{Note: model.Note{Name: "3", Wert: 3}},
+		{Note: model.Note{Name: "4", Wert: 4}, Anzahl: 1},
+		{Note: model.Note{Name: "5", Wert: 5}, Anzahl: 1},
+		{Note: model.Note{Name: "6", Wert: 6}},
+	}, statistik.Notenspiegel)
+	assert.Equal(t, []TeilStatistik{
+		{Name: "HV", DurchschnittPunkte: 38.0 / 3, DurchschnittProzent: 190.0 / 3, Verteilung: [10]int{4: 1, 6: 1, 9: 1}},
+		{Name: "LV", DurchschnittPunkte: 19, DurchschnittProzent: 190.0 / 3, Verteilung: [10]int{4: 1, 6: 1, 9: 1}},
+	}, statistik.Teile)
+
+	bewertungen[3].Gewertet = true
+	statistik = berechneStatistik(exam, model.StandardNotenschluessel, bewertungen)
+	assert.Equal(t, 4.5, statistik.Median)
+	assert.Equal(t, 50.0, statistik.DefizitAnteil())
+	assert.True(t, statistik.Drittelregel())
+}
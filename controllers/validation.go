@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"html"
+	"math"
+	"net/url"
+	"strings"
+
+	"echoTest/model"
+
+	"github.com/chasefleming/elem-go"
+	"github.com/chasefleming/elem-go/attrs"
+	"github.com/labstack/echo/v4"
+)
+
+// FieldErrors maps the name of a form field to the message shown next to it.
+type FieldErrors map[string]string
+
+// parsePunkte validates the points entered for a section and returns them
+// together with a message for the user if they cannot be used.
+func parsePunkte(locale Locale, value string, section model.Section) (float64, string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, "Bitte Punkte eingeben"
+	}
+	punkte, err := parseNumber(value)
+	if err != nil || math.IsNaN(punkte) || math.IsInf(punkte, 0) {
+		return 0, "Keine gültige Zahl"
+	}
+	if section.Max <= 0 {
+		return 0, "Für " + section.Name + " sind keine Max-Punkte festgelegt"
+	}
+	if punkte < 0 {
+		return 0, "Punkte dürfen nicht negativ sein"
+	}
+	if punkte > section.Max {
+		return 0, "Höchstens " + locale.FormatNumber(section.Max, 2) + " Punkte"
+	}
+	return punkte, ""
+}
+
+// isHTMX reports whether the request was sent by htmx rather than by a
+// plain form submission.
+func isHTMX(c echo.Context) bool {
+	return c.Request().Header.Get("HX-Request") == "true"
+}
+
+// bewertungValues returns the form values that represent the Bewertung.
+func bewertungValues(locale Locale, bewertung model.Bewertung) url.Values {
+	values := url.Values{
+		"vorname":   {bewertung.Vorname},
+		"nachname":  {bewertung.Nachname},
+		"kommentar": {bewertung.Kommentar},
+	}
+	for i, result := range bewertung.Sections {
+		values.Set(punkteField(i), locale.FormatNumber(result.Punkte, -1))
+	}
+	return values
+}
+
+// createInputNode renders a text input, marked as invalid and followed by
+// the message if there is one.
+func createInputNode(class, name, placeholder, value, message string) []elem.Node {
+	if message != "" {
+		class += " is-danger"
+	}
+	return []elem.Node{
+		elem.Input(attrs.Props{
+			attrs.Type:        "text",
+			attrs.Name:        name,
+			attrs.Class:       class,
+			attrs.Placeholder: placeholder,
+			attrs.Value:       html.EscapeString(value),
+		}),
+		elem.If[elem.Node](message != "",
+			elem.P(attrs.Props{attrs.Class: "help is-danger"}, elem.Text(html.EscapeString(message))),
+			elem.None(),
+		),
+	}
+}
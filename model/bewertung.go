@@ -1,24 +1,85 @@
 package model
 
-// Todo model
+import "time"
+
+// Bewertung is the result of one student in an exam.
 type Bewertung struct {
 	Vorname       string
 	Nachname      string
 	ID            int
-	HvPunkte      float64
-	HvProzent     float64
-	HvNote        int
-	LvPunkte      float64
-	LvProzent     float64
-	LvNote        int
+	ExamID        int
+	Sections      []SectionResult
 	GesamtProzent float64
-	GesamtNote    int
+	GesamtNote    Note
 	Gewertet      bool
+	// Grund explains why a Bewertung is not gewertet, e.g. krank.
+	Grund string
+	// Kommentar is printed on the Rückmeldebogen of the student.
+	Kommentar string
+}
+
+// SectionResult holds the points of a Bewertung in one Section of the exam.
+type SectionResult struct {
+	Punkte  float64
+	Prozent float64
+	Note    Note
+}
+
+// Section is one graded part of an exam, e.g. Hörverstehen or Grammatik.
+type Section struct {
+	Name       string
+	Max        float64
+	Gewichtung float64
+}
+
+// Exam is a single Klassenarbeit with its own sections and Bewertungen.
+type Exam struct {
+	ID                int
+	Titel             string
+	Fach              string
+	Klasse            string
+	Lehrkraft         string
+	Datum             time.Time
+	Sections          []Section
+	NotenschluesselID int
+}
+
+// Note is a grade as handed out by a Notenschluessel, e.g. "2+" or "13".
+// Wert is used wherever grades are compared or averaged.
+type Note struct {
+	Name string
+	Wert float64
+}
+
+// Notenstufe assigns its Note to all percentages up to and including Bis.
+// Defizit marks the failing grades, e.g. 5 and 6, counted for the
+// Drittelregel.
+type Notenstufe struct {
+	Note    Note
+	Bis     float64
+	Defizit bool
+}
+
+// Notenschluessel is a named grading scale. Its Stufen are sorted by Bis;
+// the last one applies to everything above the one before it.
+type Notenschluessel struct {
+	ID     int
+	Name   string
+	Stufen []Notenstufe
 }
 
-type MaxPunkte struct {
-	HvMax        float64
-	LvMax        float64
-	HvGewichtung float64
-	LvGewichtung float64
+// Bewerte computes the Prozent and Note of every section and the overall
+// result of a Bewertung from the points it holds.
+func Bewerte(exam Exam, notenschluessel Notenschluessel, bewertung Bewertung) Bewertung {
+	results := make([]SectionResult, len(exam.Sections))
+	copy(results, bewertung.Sections)
+	bewertung.GesamtProzent = 0
+	for i, section := range exam.Sections {
+		results[i].Prozent = 100.00 / section.Max * results[i].Punkte
+		results[i].Note = notenschluessel.Note(results[i].Prozent)
+		bewertung.GesamtProzent += results[i].Prozent * section.Gewichtung / 100
+	}
+	bewertung.Sections = results
+	bewertung.GesamtNote = notenschluessel.Note(bewertung.GesamtProzent)
+	return bewertung
 }
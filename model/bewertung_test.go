@@ -0,0 +1,23 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBewerte(t *testing.T) {
+	exam := Exam{Sections: []Section{
+		{Name: "HV", Max: 20, Gewichtung: 40},
+		{Name: "LV", Max: 50, Gewichtung: 60},
+	}}
+
+	bewertung := Bewerte(exam, StandardNotenschluessel, Bewertung{Sections: []SectionResult{{Punkte: 19}, {Punkte: 30}}})
+
+	assert.Equal(t, 95.0, bewertung.Sections[0].Prozent)
+	assert.Equal(t, "1", bewertung.Sections[0].Note.Name)
+	assert.Equal(t, 60.0, bewertung.Sections[1].Prozent)
+	assert.Equal(t, "4", bewertung.Sections[1].Note.Name)
+	assert.InDelta(t, 74.0, bewertung.GesamtProzent, 0.001)
+	assert.Equal(t, Note{Name: "3", Wert: 3}, bewertung.GesamtNote)
+}
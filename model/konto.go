@@ -0,0 +1,56 @@
+package model
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrExists is returned by a Store when a record with the same name exists.
+var ErrExists = errors.New("existiert bereits")
+
+// minPasswortLaenge is the shortest password NewKonto accepts.
+const minPasswortLaenge = 8
+
+// Konto is the login of a teacher. Only the bcrypt hash of the password is
+// stored.
+type Konto struct {
+	ID           int
+	Name         string
+	PasswortHash []byte
+}
+
+// NewKonto returns a Konto with the hash of the password.
+func NewKonto(name, passwort string) (Konto, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Konto{}, errors.New("Bitte einen Namen eingeben")
+	}
+	if len([]rune(passwort)) < minPasswortLaenge {
+		return Konto{}, errors.New("Das Passwort muss mindestens 8 Zeichen lang sein")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(passwort), bcrypt.DefaultCost)
+	if err != nil {
+		return Konto{}, err
+	}
+	return Konto{Name: name, PasswortHash: hash}, nil
+}
+
+// dummyHash is compared against when no Konto exists, so a failed login
+// takes as long for unknown names as for wrong passwords.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("kein Passwort"), bcrypt.DefaultCost)
+
+// PruefeAnmeldung returns the Konto of the Store with the name if the
+// password matches.
+func PruefeAnmeldung(store Store, name, passwort string) (Konto, bool) {
+	konto, err := store.LoadKonto(strings.TrimSpace(name))
+	if err != nil {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(passwort))
+		return Konto{}, false
+	}
+	if bcrypt.CompareHashAndPassword(konto.PasswortHash, []byte(passwort)) != nil {
+		return Konto{}, false
+	}
+	return konto, true
+}
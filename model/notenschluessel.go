@@ -0,0 +1,72 @@
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// StandardNotenschluessel is the scale new stores start with and the
+// fallback for exams without a valid scale.
+var StandardNotenschluessel = Notenschluessel{
+	ID:   1,
+	Name: "Standard (1–6)",
+	Stufen: []Notenstufe{
+		{Note: Note{Name: "6", Wert: 6}, Bis: 22, Defizit: true},
+		{Note: Note{Name: "5", Wert: 5}, Bis: 49, Defizit: true},
+		{Note: Note{Name: "4", Wert: 4}, Bis: 64},
+		{Note: Note{Name: "3", Wert: 3}, Bis: 79},
+		{Note: Note{Name: "2", Wert: 2}, Bis: 94},
+		{Note: Note{Name: "1", Wert: 1}, Bis: 100},
+	},
+}
+
+// oberstufeGrenzen are the upper bounds for 0 to 15 Punkte.
+var oberstufeGrenzen = []float64{19.99, 26.99, 32.99, 39.99, 44.99, 49.99, 54.99, 59.99, 64.99, 69.99, 74.99, 79.99, 84.99, 89.99, 94.99, 100}
+
+// DefaultNotenschluessel returns the scales a new store starts with.
+func DefaultNotenschluessel() []Notenschluessel {
+	tendenzen := Notenschluessel{ID: 2, Name: "Tendenzen (1+ bis 6)"}
+	oberstufe := Notenschluessel{ID: 3, Name: "Oberstufe (0–15 Punkte)"}
+	namen := []string{"6", "5-", "5", "5+", "4-", "4", "4+", "3-", "3", "3+", "2-", "2", "2+", "1-", "1", "1+"}
+	werte := []float64{6, 5.3, 5, 4.7, 4.3, 4, 3.7, 3.3, 3, 2.7, 2.3, 2, 1.7, 1.3, 1, 0.7}
+	for i, bis := range oberstufeGrenzen {
+		tendenzen.Stufen = append(tendenzen.Stufen, Notenstufe{Note: Note{Name: namen[i], Wert: werte[i]}, Bis: bis, Defizit: i < 4})
+		oberstufe.Stufen = append(oberstufe.Stufen, Notenstufe{Note: Note{Name: strconv.Itoa(i), Wert: float64(i)}, Bis: bis, Defizit: i < 5})
+	}
+	return []Notenschluessel{StandardNotenschluessel, tendenzen, oberstufe}
+}
+
+// Note returns the grade for the given percentage.
+func (n Notenschluessel) Note(prozent float64) Note {
+	if len(n.Stufen) == 0 {
+		return Note{}
+	}
+	for _, stufe := range n.Stufen {
+		if prozent <= stufe.Bis {
+			return stufe.Note
+		}
+	}
+	return n.Stufen[len(n.Stufen)-1].Note
+}
+
+// Defizit reports whether the Note is a failing grade in this scale.
+func (n Notenschluessel) Defizit(note Note) bool {
+	for _, stufe := range n.Stufen {
+		if stufe.Note.Name == note.Name {
+			return stufe.Defizit
+		}
+	}
+	return false
+}
+
+// UnmarshalJSON also accepts the plain numbers stored before Noten came
+// from a Notenschluessel.
+func (n *Note) UnmarshalJSON(data []byte) error {
+	var wert float64
+	if err := json.Unmarshal(data, &wert); err == nil {
+		*n = Note{Name: strconv.FormatFloat(wert, 'f', -1, 64), Wert: wert}
+		return nil
+	}
+	type note Note
+	return json.Unmarshal(data, (*note)(n))
+}
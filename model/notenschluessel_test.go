@@ -0,0 +1,45 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotenschluesselNote(t *testing.T) {
+	tests := []struct {
+		prozent float64
+		note    string
+	}{
+		{0, "6"},
+		{22, "6"},
+		{22.5, "5"},
+		{49, "5"},
+		{64, "4"},
+		{79.5, "2"},
+		{94, "2"},
+		{94.01, "1"},
+		{100, "1"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.note, StandardNotenschluessel.Note(tt.prozent).Name, "%.2f %%", tt.prozent)
+	}
+
+	oberstufe := DefaultNotenschluessel()[2]
+	assert.Equal(t, Note{Name: "15", Wert: 15}, oberstufe.Note(95))
+	assert.Equal(t, Note{Name: "4", Wert: 4}, oberstufe.Note(40))
+	assert.Equal(t, Note{Name: "0", Wert: 0}, oberstufe.Note(19.5))
+
+	tendenzen := DefaultNotenschluessel()[1]
+	assert.Equal(t, Note{Name: "2+", Wert: 1.7}, tendenzen.Note(82))
+}
+
+func TestNoteUnmarshalLegacyNumber(t *testing.T) {
+	var result SectionResult
+	assert.NoError(t, json.Unmarshal([]byte(`{"Punkte":10,"Prozent":50,"Note":4}`), &result))
+	assert.Equal(t, Note{Name: "4", Wert: 4}, result.Note)
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"Note":{"Name":"2-","Wert":2.3}}`), &result))
+	assert.Equal(t, Note{Name: "2-", Wert: 2.3}, result.Note)
+}
@@ -0,0 +1,495 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned by a Store when no record matches the given ID.
+var ErrNotFound = errors.New("nicht gefunden")
+
+// Store persists the exams, the Bewertungen belonging to them and the
+// grading scales they use.
+type Store interface {
+	ListExams() ([]Exam, error)
+	LoadExam(id int) (Exam, error)
+	SaveExam(exam Exam) (Exam, error)
+	UpdateExam(exam Exam) error
+	DeleteExam(id int) error
+
+	List(examID int) ([]Bewertung, error)
+	Load(id int) (Bewertung, error)
+	// Save returns ErrExists if the exam has a Bewertung with the same
+	// names already.
+	Save(bewertung Bewertung) (Bewertung, error)
+	Update(bewertung Bewertung) error
+	// ToggleGewertet flips whether the Bewertung is gewertet and stores the
+	// Grund if it no longer is.
+	ToggleGewertet(id int, grund string) (Bewertung, error)
+	Delete(id int) error
+
+	ListNotenschluessel() ([]Notenschluessel, error)
+	LoadNotenschluessel(id int) (Notenschluessel, error)
+	SaveNotenschluessel(notenschluessel Notenschluessel) (Notenschluessel, error)
+	UpdateNotenschluessel(notenschluessel Notenschluessel) error
+	DeleteNotenschluessel(id int) error
+
+	ListKonten() ([]Konto, error)
+	// LoadKonto finds a Konto by its name, ignoring case.
+	LoadKonto(name string) (Konto, error)
+	SaveKonto(konto Konto) (Konto, error)
+
+	// Close writes pending changes; the Store must not be used afterwards.
+	Close() error
+}
+
+type storeData struct {
+	Exams           []Exam
+	Bewertungen     []Bewertung
+	Notenschluessel []Notenschluessel
+	Konten          []Konto
+	LetzteIDs       letzteIDs
+}
+
+// letzteIDs are the highest IDs handed out so far. IDs are never reused, so
+// a request for a deleted record cannot hit a newer one.
+type letzteIDs struct {
+	Exam            int
+	Bewertung       int
+	Notenschluessel int
+	Konto           int
+}
+
+// legacyMaxPunkte are the fixed HV/LV parts used before exams had sections.
+type legacyMaxPunkte struct {
+	HvMax        float64
+	LvMax        float64
+	HvGewichtung float64
+	LvGewichtung float64
+}
+
+type legacyExam struct {
+	ID        int
+	MaxPunkte *legacyMaxPunkte
+}
+
+type legacyBewertung struct {
+	ID       int
+	HvPunkte float64
+	LvPunkte float64
+}
+
+// legacyNotenschluessel tells whether a scale was stored before Notenstufen
+// had the Defizit flag.
+type legacyNotenschluessel struct {
+	ID     int
+	Stufen []struct {
+		Defizit *bool
+	}
+}
+
+// legacyData holds the fields of older file formats that storeData no
+// longer knows about.
+type legacyData struct {
+	Exams           []legacyExam
+	Bewertungen     []legacyBewertung
+	MaxPunkte       *legacyMaxPunkte
+	Notenschluessel []legacyNotenschluessel
+}
+
+// JSONStore keeps all data in memory and writes it to a single JSON file
+// after every change.
+type JSONStore struct {
+	mu   sync.Mutex
+	path string
+	data storeData
+}
+
+var _ Store = (*JSONStore)(nil)
+
+// NewJSONStore reads the file at path, migrating older formats. A missing
+// file is created with the default grading scales on the first change.
+func NewJSONStore(path string) (*JSONStore, error) {
+	s := &JSONStore{path: path}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.data.Notenschluessel = DefaultNotenschluessel()
+		s.updateLetzteIDs()
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &s.data); err != nil {
+		return nil, err
+	}
+	var legacy legacyData
+	if err := json.Unmarshal(content, &legacy); err != nil {
+		return nil, err
+	}
+	s.migrate(legacy)
+	s.updateLetzteIDs()
+	return s, nil
+}
+
+// migrate converts older file formats: Bewertungen of the single-exam
+// format move into an exam of their own, exams with fixed HV/LV parts get
+// an HV and an LV section and files without grading scales get the
+// default ones. Default scales stored without Defizit flags get them from
+// DefaultNotenschluessel.
+func (s *JSONStore) migrate(legacy legacyData) {
+	if len(s.data.Notenschluessel) == 0 {
+		s.data.Notenschluessel = DefaultNotenschluessel()
+	}
+
+	for _, old := range legacy.Notenschluessel {
+		if len(old.Stufen) == 0 || old.Stufen[0].Defizit != nil {
+			continue
+		}
+		i := s.notenschluesselIndex(old.ID)
+		for _, defaults := range DefaultNotenschluessel() {
+			if i < 0 || defaults.ID != old.ID {
+				continue
+			}
+			for j, stufe := range s.data.Notenschluessel[i].Stufen {
+				s.data.Notenschluessel[i].Stufen[j].Defizit = defaults.Defizit(stufe.Note)
+			}
+		}
+	}
+
+	if len(s.data.Exams) == 0 && len(s.data.Bewertungen) > 0 {
+		s.data.Exams = []Exam{{ID: 1, Titel: "Englischarbeit", NotenschluesselID: StandardNotenschluessel.ID}}
+		legacy.Exams = []legacyExam{{ID: 1, MaxPunkte: legacy.MaxPunkte}}
+		for i := range s.data.Bewertungen {
+			s.data.Bewertungen[i].ExamID = 1
+		}
+	}
+
+	for _, old := range legacy.Exams {
+		i := s.examIndex(old.ID)
+		if i < 0 || old.MaxPunkte == nil || len(s.data.Exams[i].Sections) > 0 {
+			continue
+		}
+		exam := &s.data.Exams[i]
+		exam.NotenschluesselID = StandardNotenschluessel.ID
+		exam.Sections = []Section{
+			{Name: "HV", Max: old.MaxPunkte.HvMax, Gewichtung: old.MaxPunkte.HvGewichtung},
+			{Name: "LV", Max: old.MaxPunkte.LvMax, Gewichtung: old.MaxPunkte.LvGewichtung},
+		}
+		for _, bewertung := range legacy.Bewertungen {
+			j := s.index(bewertung.ID)
+			if j < 0 || s.data.Bewertungen[j].ExamID != exam.ID {
+				continue
+			}
+			s.data.Bewertungen[j].Sections = []SectionResult{
+				{Punkte: bewertung.HvPunkte},
+				{Punkte: bewertung.LvPunkte},
+			}
+			s.data.Bewertungen[j] = Bewerte(*exam, StandardNotenschluessel, s.data.Bewertungen[j])
+		}
+	}
+}
+
+// updateLetzteIDs raises the counters to the highest stored IDs, which
+// files written before the counters existed need.
+func (s *JSONStore) updateLetzteIDs() {
+	for _, exam := range s.data.Exams {
+		s.data.LetzteIDs.Exam = max(s.data.LetzteIDs.Exam, exam.ID)
+	}
+	for _, bewertung := range s.data.Bewertungen {
+		s.data.LetzteIDs.Bewertung = max(s.data.LetzteIDs.Bewertung, bewertung.ID)
+	}
+	for _, notenschluessel := range s.data.Notenschluessel {
+		s.data.LetzteIDs.Notenschluessel = max(s.data.LetzteIDs.Notenschluessel, notenschluessel.ID)
+	}
+	for _, konto := range s.data.Konten {
+		s.data.LetzteIDs.Konto = max(s.data.LetzteIDs.Konto, konto.ID)
+	}
+}
+
+func (s *JSONStore) ListExams() ([]Exam, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Exam(nil), s.data.Exams...), nil
+}
+
+func (s *JSONStore) LoadExam(id int) (Exam, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.examIndex(id)
+	if i < 0 {
+		return Exam{}, ErrNotFound
+	}
+	return s.data.Exams[i], nil
+}
+
+func (s *JSONStore) SaveExam(exam Exam) (Exam, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.LetzteIDs.Exam++
+	exam.ID = s.data.LetzteIDs.Exam
+	s.data.Exams = append(s.data.Exams, exam)
+	return exam, s.persist()
+}
+
+func (s *JSONStore) UpdateExam(exam Exam) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.examIndex(exam.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.data.Exams[i] = exam
+	return s.persist()
+}
+
+// DeleteExam removes the exam together with all of its Bewertungen.
+func (s *JSONStore) DeleteExam(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.examIndex(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.data.Exams = append(s.data.Exams[:i], s.data.Exams[i+1:]...)
+	bewertungen := s.data.Bewertungen[:0]
+	for _, bewertung := range s.data.Bewertungen {
+		if bewertung.ExamID != id {
+			bewertungen = append(bewertungen, bewertung)
+		}
+	}
+	s.data.Bewertungen = bewertungen
+	return s.persist()
+}
+
+func (s *JSONStore) List(examID int) ([]Bewertung, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var bewertungen []Bewertung
+	for _, bewertung := range s.data.Bewertungen {
+		if bewertung.ExamID == examID {
+			bewertungen = append(bewertungen, bewertung)
+		}
+	}
+	return bewertungen, nil
+}
+
+func (s *JSONStore) Load(id int) (Bewertung, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(id)
+	if i < 0 {
+		return Bewertung{}, ErrNotFound
+	}
+	return s.data.Bewertungen[i], nil
+}
+
+// Save assigns a new ID to the Bewertung and stores it. The check for a
+// Bewertung with the same names runs under the lock, so two concurrent
+// requests cannot both add the same student.
+func (s *JSONStore) Save(bewertung Bewertung) (Bewertung, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.examIndex(bewertung.ExamID) < 0 {
+		return Bewertung{}, ErrNotFound
+	}
+	for _, other := range s.data.Bewertungen {
+		if other.ExamID == bewertung.ExamID && other.Vorname == bewertung.Vorname && other.Nachname == bewertung.Nachname {
+			return Bewertung{}, ErrExists
+		}
+	}
+	s.data.LetzteIDs.Bewertung++
+	bewertung.ID = s.data.LetzteIDs.Bewertung
+	s.data.Bewertungen = append(s.data.Bewertungen, bewertung)
+	return bewertung, s.persist()
+}
+
+func (s *JSONStore) Update(bewertung Bewertung) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(bewertung.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.data.Bewertungen[i] = bewertung
+	return s.persist()
+}
+
+func (s *JSONStore) ToggleGewertet(id int, grund string) (Bewertung, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(id)
+	if i < 0 {
+		return Bewertung{}, ErrNotFound
+	}
+	bewertung := &s.data.Bewertungen[i]
+	bewertung.Gewertet = !bewertung.Gewertet
+	bewertung.Grund = ""
+	if !bewertung.Gewertet {
+		bewertung.Grund = grund
+	}
+	return *bewertung, s.persist()
+}
+
+func (s *JSONStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.data.Bewertungen = append(s.data.Bewertungen[:i], s.data.Bewertungen[i+1:]...)
+	return s.persist()
+}
+
+func (s *JSONStore) ListNotenschluessel() ([]Notenschluessel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Notenschluessel(nil), s.data.Notenschluessel...), nil
+}
+
+func (s *JSONStore) LoadNotenschluessel(id int) (Notenschluessel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.notenschluesselIndex(id)
+	if i < 0 {
+		return Notenschluessel{}, ErrNotFound
+	}
+	return s.data.Notenschluessel[i], nil
+}
+
+func (s *JSONStore) SaveNotenschluessel(notenschluessel Notenschluessel) (Notenschluessel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.LetzteIDs.Notenschluessel++
+	notenschluessel.ID = s.data.LetzteIDs.Notenschluessel
+	s.data.Notenschluessel = append(s.data.Notenschluessel, notenschluessel)
+	return notenschluessel, s.persist()
+}
+
+func (s *JSONStore) UpdateNotenschluessel(notenschluessel Notenschluessel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.notenschluesselIndex(notenschluessel.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.data.Notenschluessel[i] = notenschluessel
+	return s.persist()
+}
+
+func (s *JSONStore) DeleteNotenschluessel(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.notenschluesselIndex(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.data.Notenschluessel = append(s.data.Notenschluessel[:i], s.data.Notenschluessel[i+1:]...)
+	return s.persist()
+}
+
+func (s *JSONStore) ListKonten() ([]Konto, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Konto(nil), s.data.Konten...), nil
+}
+
+func (s *JSONStore) LoadKonto(name string) (Konto, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.kontoIndex(name)
+	if i < 0 {
+		return Konto{}, ErrNotFound
+	}
+	return s.data.Konten[i], nil
+}
+
+// SaveKonto assigns a new ID to the Konto and stores it. It returns
+// ErrExists if the name is taken.
+func (s *JSONStore) SaveKonto(konto Konto) (Konto, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.kontoIndex(konto.Name) >= 0 {
+		return Konto{}, ErrExists
+	}
+	s.data.LetzteIDs.Konto++
+	konto.ID = s.data.LetzteIDs.Konto
+	s.data.Konten = append(s.data.Konten, konto)
+	return konto, s.persist()
+}
+
+func (s *JSONStore) kontoIndex(name string) int {
+	for i, konto := range s.data.Konten {
+		if strings.EqualFold(konto.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *JSONStore) notenschluesselIndex(id int) int {
+	for i, notenschluessel := range s.data.Notenschluessel {
+		if notenschluessel.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *JSONStore) examIndex(id int) int {
+	for i, exam := range s.data.Exams {
+		if exam.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *JSONStore) index(id int) int {
+	for i, bewertung := range s.data.Bewertungen {
+		if bewertung.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Close waits for running changes and writes the data once more. Every
+// change is already persisted when it is made, so this only matters if the
+// last write failed.
+func (s *JSONStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.persist()
+}
+
+// persist writes the data to a temporary file next to the target and
+// renames it, so a crash never leaves a half-written file behind.
+func (s *JSONStore) persist() error {
+	content, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
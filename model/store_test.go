@@ -0,0 +1,219 @@
+package model
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bewertungen.json")
+	s, err := NewJSONStore(path)
+	assert.NoError(t, err)
+
+	exam, err := s.SaveExam(Exam{Titel: "Englischarbeit", Sections: []Section{{Name: "HV", Max: 20, Gewichtung: 100}}})
+	assert.NoError(t, err)
+	other, err := s.SaveExam(Exam{Titel: "Vokabeltest"})
+	assert.NoError(t, err)
+
+	anna, err := s.Save(Bewertung{ExamID: exam.ID, Vorname: "Anna", Nachname: "Muster"})
+	assert.NoError(t, err)
+	ben, err := s.Save(Bewertung{ExamID: exam.ID, Vorname: "Ben", Nachname: "Beispiel"})
+	assert.NoError(t, err)
+	_, err = s.Save(Bewertung{ExamID: other.ID, Vorname: "Cem", Nachname: "Test"})
+	assert.NoError(t, err)
+	_, err = s.Save(Bewertung{ExamID: 99, Nachname: "Niemand"})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	ben.GesamtNote = Note{Name: "2", Wert: 2}
+	assert.NoError(t, s.Update(ben))
+	assert.NoError(t, s.Delete(anna.ID))
+	assert.ErrorIs(t, s.Delete(anna.ID), ErrNotFound)
+	assert.NoError(t, s.DeleteExam(other.ID))
+
+	reopened, err := NewJSONStore(path)
+	assert.NoError(t, err)
+	exams, err := reopened.ListExams()
+	assert.NoError(t, err)
+	assert.Equal(t, []Exam{exam}, exams)
+	bewertungen, err := reopened.List(exam.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []Bewertung{ben}, bewertungen)
+	bewertungen, err = reopened.List(other.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, bewertungen)
+}
+
+func TestJSONStoreMigratesSingleExamFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bewertungen.json")
+	legacy := `{"Bewertungen":[{"ID":1,"Nachname":"Muster","HvPunkte":10,"LvPunkte":15}],"MaxPunkte":{"HvMax":20,"LvMax":30,"HvGewichtung":50,"LvGewichtung":50}}`
+	assert.NoError(t, os.WriteFile(path, []byte(legacy), 0o644))
+
+	s, err := NewJSONStore(path)
+	assert.NoError(t, err)
+	exams, err := s.ListExams()
+	assert.NoError(t, err)
+	assert.Len(t, exams, 1)
+	assert.Equal(t, []Section{
+		{Name: "HV", Max: 20, Gewichtung: 50},
+		{Name: "LV", Max: 30, Gewichtung: 50},
+	}, exams[0].Sections)
+	bewertungen, err := s.List(exams[0].ID)
+	assert.NoError(t, err)
+	assert.Len(t, bewertungen, 1)
+	assert.Equal(t, 15.0, bewertungen[0].Sections[1].Punkte)
+	assert.Equal(t, 50.0, bewertungen[0].Sections[1].Prozent)
+}
+
+func TestJSONStoreMigratesDefizit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bewertungen.json")
+	stored := `{"Notenschluessel":[
+		{"ID":1,"Name":"Standard","Stufen":[{"Note":{"Name":"6","Wert":6},"Bis":22},{"Note":{"Name":"5","Wert":5},"Bis":49},{"Note":{"Name":"4","Wert":4},"Bis":100}]},
+		{"ID":7,"Name":"Eigener","Stufen":[{"Note":{"Name":"6","Wert":6},"Bis":50},{"Note":{"Name":"1","Wert":1},"Bis":100}]}
+	]}`
+	assert.NoError(t, os.WriteFile(path, []byte(stored), 0o644))
+
+	s, err := NewJSONStore(path)
+	assert.NoError(t, err)
+	standard, err := s.LoadNotenschluessel(1)
+	assert.NoError(t, err)
+	assert.True(t, standard.Stufen[0].Defizit)
+	assert.True(t, standard.Stufen[1].Defizit)
+	assert.False(t, standard.Stufen[2].Defizit)
+	eigener, err := s.LoadNotenschluessel(7)
+	assert.NoError(t, err)
+	assert.False(t, eigener.Stufen[0].Defizit)
+}
+
+func TestJSONStoreNeverReusesIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bewertungen.json")
+	s, err := NewJSONStore(path)
+	assert.NoError(t, err)
+	exam, err := s.SaveExam(Exam{Titel: "Englischarbeit"})
+	assert.NoError(t, err)
+
+	first, err := s.Save(Bewertung{ExamID: exam.ID, Nachname: "Muster"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Delete(first.ID))
+
+	reopened, err := NewJSONStore(path)
+	assert.NoError(t, err)
+	second, err := reopened.Save(Bewertung{ExamID: exam.ID, Nachname: "Beispiel"})
+	assert.NoError(t, err)
+	assert.Greater(t, second.ID, first.ID)
+}
+
+func TestJSONStoreConcurrentSaves(t *testing.T) {
+	s, err := NewJSONStore(filepath.Join(t.TempDir(), "bewertungen.json"))
+	assert.NoError(t, err)
+	exam, err := s.SaveExam(Exam{Titel: "Englischarbeit"})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bewertung, err := s.Save(Bewertung{ExamID: exam.ID, Nachname: "Muster " + strconv.Itoa(i)})
+			assert.NoError(t, err)
+			bewertung.Gewertet = true
+			assert.NoError(t, s.Update(bewertung))
+		}(i)
+	}
+	wg.Wait()
+
+	bewertungen, err := s.List(exam.ID)
+	assert.NoError(t, err)
+	assert.Len(t, bewertungen, 20)
+	ids := map[int]bool{}
+	for _, bewertung := range bewertungen {
+		ids[bewertung.ID] = true
+	}
+	assert.Len(t, ids, 20)
+}
+
+func TestJSONStoreConcurrentDuplicatesAndToggles(t *testing.T) {
+	s, err := NewJSONStore(filepath.Join(t.TempDir(), "bewertungen.json"))
+	assert.NoError(t, err)
+	exam, err := s.SaveExam(Exam{Titel: "Englischarbeit"})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var saved, exists int
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Save(Bewertung{ExamID: exam.ID, Vorname: "Anna", Nachname: "Muster"})
+			mu.Lock()
+			defer mu.Unlock()
+			if errors.Is(err, ErrExists) {
+				exists++
+			} else if assert.NoError(t, err) {
+				saved++
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, saved)
+	assert.Equal(t, 19, exists)
+
+	bewertungen, err := s.List(exam.ID)
+	assert.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.ToggleGewertet(bewertungen[0].ID, "krank")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	// An even number of toggles leaves the Bewertung as it was
+	bewertung, err := s.Load(bewertungen[0].ID)
+	assert.NoError(t, err)
+	assert.False(t, bewertung.Gewertet)
+	assert.Equal(t, "krank", bewertung.Grund)
+
+	bewertung, err = s.ToggleGewertet(bewertung.ID, "krank")
+	assert.NoError(t, err)
+	assert.True(t, bewertung.Gewertet)
+	assert.Empty(t, bewertung.Grund)
+}
+
+func TestJSONStoreKonten(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bewertungen.json")
+	s, err := NewJSONStore(path)
+	assert.NoError(t, err)
+
+	konto, err := NewKonto(" Frau Meier ", "geheim123")
+	assert.NoError(t, err)
+	assert.Equal(t, "Frau Meier", konto.Name)
+	assert.NotContains(t, string(konto.PasswortHash), "geheim123")
+	konto, err = s.SaveKonto(konto)
+	assert.NoError(t, err)
+	_, err = s.SaveKonto(Konto{Name: "frau meier"})
+	assert.ErrorIs(t, err, ErrExists)
+
+	reopened, err := NewJSONStore(path)
+	assert.NoError(t, err)
+	loaded, err := reopened.LoadKonto("FRAU MEIER")
+	assert.NoError(t, err)
+	assert.Equal(t, konto, loaded)
+
+	_, ok := PruefeAnmeldung(reopened, "Frau Meier", "geheim123")
+	assert.True(t, ok)
+	_, ok = PruefeAnmeldung(reopened, "Frau Meier", "falsch")
+	assert.False(t, ok)
+	_, ok = PruefeAnmeldung(reopened, "Herr Schulz", "geheim123")
+	assert.False(t, ok)
+
+	_, err = NewKonto("Herr Schulz", "kurz")
+	assert.Error(t, err)
+}
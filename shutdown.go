@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"echoTest/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+// shutdownTimeout is how long running requests may take before the server
+// closes their connections.
+const shutdownTimeout = 10 * time.Second
+
+// shutdownRequests receives a value when the user ends the application.
+var shutdownRequests = make(chan struct{}, 1)
+
+// requestShutdown asks main to shut down. Repeated requests are ignored.
+func requestShutdown() {
+	select {
+	case shutdownRequests <- struct{}{}:
+	default:
+	}
+}
+
+// shutdown stops the server after running requests have finished and
+// closes the store.
+func shutdown(e *echo.Echo, store model.Store) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return errors.Join(e.Shutdown(ctx), store.Close())
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"echoTest/model"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestShutdown(t *testing.T) {
+	requestShutdown()
+	requestShutdown()
+	<-shutdownRequests
+	select {
+	case <-shutdownRequests:
+		t.Fatal("a repeated request was queued")
+	default:
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bewertungen.json")
+	jsonStore, err := model.NewJSONStore(path)
+	assert.NoError(t, err)
+	_, err = jsonStore.SaveExam(model.Exam{Titel: "Mathearbeit"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, shutdown(echo.New(), jsonStore))
+
+	reopened, err := model.NewJSONStore(path)
+	assert.NoError(t, err)
+	exams, err := reopened.ListExams()
+	assert.NoError(t, err)
+	assert.Len(t, exams, 1)
+}